@@ -0,0 +1,153 @@
+// Command apikey issues, lists and revokes API keys without going through the HTTP API,
+// e.g. to create the first admin key:
+//
+//	apikey -config ./config.yaml issue -name bootstrap -scopes admin
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/drivers"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `usage: apikey [-config path] <command> [flags]
+
+commands:
+  issue  -name <name> -scopes <read,write,admin> [-library <library>] [-expires-in <duration>]
+  list
+  revoke -id <id>
+`
+
+func main() {
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).
+		Level(zerolog.WarnLevel).
+		With().
+		Timestamp().
+		Logger()
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	cfgPath, err := config.ParseCLI()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse CLI")
+	}
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cfg, err := config.NewConfig(cfgPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config")
+	}
+	connURL := fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.Db.User,
+		cfg.Db.Password,
+		cfg.Db.Host,
+		cfg.Db.Port,
+		cfg.Db.Name,
+	)
+	db, err := drivers.Connect(connURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	s := services.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+
+	switch args[0] {
+	case "issue":
+		err = issue(s, args[1:])
+	case "list":
+		err = list(s)
+	case "revoke":
+		err = revoke(s, args[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to %s api key", args[0])
+	}
+}
+
+func issue(s services.IAPIKeyService, args []string) error {
+	fs := flag.NewFlagSet("issue", flag.ExitOnError)
+	name := fs.String("name", "", "name of the client using the key")
+	scopes := fs.String("scopes", models.ScopeRead, "comma separated scopes: read, write, admin")
+	library := fs.String("library", "", "library the key is bound to, any library if not set")
+	expiresIn := fs.Duration("expires-in", 0, "lifetime of the key, e.g. 720h, never expires if not set")
+	fs.Parse(args)
+	if *name == "" {
+		return fmt.Errorf("name is required")
+	}
+	scopeList := strings.Split(*scopes, ",")
+	for _, scope := range scopeList {
+		if !slices.Contains([]string{models.ScopeRead, models.ScopeWrite, models.ScopeAdmin}, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if *library != "" && !utils.IsLibraryName(*library) {
+		return fmt.Errorf("invalid library name %q", *library)
+	}
+	key, err := s.IssueKey(context.Background(), *name, scopeList, *library, *expiresIn)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Issued key %d for %s, it is shown only once:\n%s\n", *key.ID, key.Name, key.Key)
+	return nil
+}
+
+func list(s services.IAPIKeyService) error {
+	keys, err := s.GetKeys(context.Background(), "")
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tLIBRARY\tEXPIRES\tLAST USED\tREVOKED")
+	for _, key := range keys {
+		library := "*"
+		if key.Library != nil {
+			library = *key.Library
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			*key.ID, key.Name, key.Prefix, strings.Join(key.Scopes, ","), library,
+			formatTime(key.ExpiresAt), formatTime(key.LastUsedAt), formatTime(key.RevokedAt))
+	}
+	return w.Flush()
+}
+
+func revoke(s services.IAPIKeyService, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := fs.String("id", "", "id of the key")
+	fs.Parse(args)
+	keyID, err := strconv.Atoi(*id)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", *id)
+	}
+	if err := s.RevokeKey(context.Background(), keyID, ""); err != nil {
+		return err
+	}
+	fmt.Printf("Revoked key %d\n", keyID)
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,27 +1,43 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"music-lib/internal/config"
 	"music-lib/internal/db/drivers"
+	"music-lib/internal/db/models"
 	"music-lib/internal/db/repository"
 	"music-lib/internal/handlers"
+	"music-lib/internal/jobs"
+	"music-lib/internal/logging"
+	"music-lib/internal/middlewares"
 	"music-lib/internal/services"
+	"music-lib/internal/tracing"
+	"music-lib/internal/utils"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 	_ "music-lib/docs"
 )
 
 var (
-	db  *sqlx.DB
-	cfg *config.Config
+	db      *sqlx.DB
+	cfg     *config.Config
+	cfgPath string
 )
 
 func init() {
@@ -33,7 +49,8 @@ func init() {
 		Caller().
 		Logger()
 	log.Info().Msg("Logger initialized")
-	cfgPath, err := config.ParseCLI()
+	var err error
+	cfgPath, err = config.ParseCLI()
 	// Parse CLI arguments
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to parse CLI")
@@ -43,6 +60,12 @@ func init() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to read config")
 	}
+	log.Logger, err = logging.New(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure logging")
+	}
+	// Used by log.Ctx outside of requests, in background jobs
+	zerolog.DefaultContextLogger = &log.Logger
 	log.Info().Msg("Config loaded")
 	// Initialize database
 	connURL := fmt.Sprintf(
@@ -57,16 +80,32 @@ func init() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
-	log.Info().Msg("Database connected")
+	// Password in the URL is redacted by the logger
+	log.Info().Str("url", connURL).Msg("Database connected")
 }
 
 // @title Songs API
 // @version 1.0
 // @description This is an API for managing songs library.
+// @description Songs, artists, albums and playlists are kept in separate libraries, named by X-Library header or by subdomain, the default library is used otherwise.
 
 // @host localhost:8080
 // @BasePath /api1/public
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT with sub and exp claims as "Bearer <token>", required for write endpoints
+
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key issued to a service client
 func main() {
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
 	// Setup services
 	songRepo := repository.NewSongRepository(db)
 	songService := services.NewSongService(songRepo)
@@ -74,33 +113,211 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize music info service")
 	}
+	artistRepo := repository.NewArtistRepository(db)
+	artistService := services.NewArtistService(artistRepo, songRepo)
+	albumRepo := repository.NewAlbumRepository(db)
+	albumService := services.NewAlbumService(albumRepo, songRepo)
+	tagRepo := repository.NewTagRepository(db)
+	tagService := services.NewTagService(tagRepo, songRepo)
+	playlistRepo := repository.NewPlaylistRepository(db)
+	playlistService := services.NewPlaylistService(playlistRepo, songRepo)
+	favoriteRepo := repository.NewFavoriteRepository(db)
+	favoriteService := services.NewFavoriteService(favoriteRepo, songRepo)
+	ratingRepo := repository.NewRatingRepository(db)
+	ratingService := services.NewRatingService(ratingRepo, songRepo)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg)
+	// Connection pool stats of the database
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db.DB, cfg.Db.Name))
+	healthRepo := repository.NewHealthRepository(db)
+	healthService := services.NewHealthService(healthRepo, musicInfoService, cfg)
+	policyService, err := services.NewPolicyService(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load access policies")
+	}
+	// Background jobs, stopped on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	workers := sync.WaitGroup{}
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		jobs.Every(
+			jobsCtx,
+			"idempotency-cleanup",
+			time.Duration(cfg.Idempotency.CleanupInterval)*time.Minute,
+			idempotencyService.PurgeExpired)
+	}()
+	go func() {
+		defer workers.Done()
+		jobs.Every(
+			jobsCtx,
+			"trash-purge",
+			time.Duration(cfg.Trash.PurgeInterval)*time.Minute,
+			func(ctx context.Context) error {
+				return songService.PurgeTrash(ctx, time.Duration(cfg.Trash.Retention)*time.Hour)
+			})
+	}()
+	// Settings changed in config without restart
+	cors := middlewares.NewCORS(cfg)
+	rateLimiter := middlewares.NewRateLimiter(cfg)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		config.Watch(
+			jobsCtx,
+			cfgPath,
+			time.Duration(cfg.Reload.Interval)*time.Second,
+			func(newCfg *config.Config) {
+				if err := logging.SetLevel(newCfg); err != nil {
+					log.Error().Err(err).Msg("Failed to change log level")
+				}
+				musicInfoService.Configure(newCfg)
+				cors.Configure(newCfg)
+				rateLimiter.Configure(newCfg)
+				log.Info().Msg("Config reloaded")
+			})
+	}()
 	// Setup controllers
-	songController := handlers.NewSongController(songService, musicInfoService, cfg)
+	songController := handlers.NewSongController(songService, musicInfoService, policyService, cfg)
+	artistController := handlers.NewArtistController(artistService, cfg)
+	albumController := handlers.NewAlbumController(albumService, cfg)
+	tagController := handlers.NewTagController(tagService, cfg)
+	playlistController := handlers.NewPlaylistController(playlistService, cfg)
+	favoriteController := handlers.NewFavoriteController(favoriteService, cfg)
+	ratingController := handlers.NewRatingController(ratingService, cfg)
+	apiKeyController := handlers.NewAPIKeyController(apiKeyService, cfg)
+	healthController := handlers.NewHealthController(healthService)
+	jwtAuth, err := middlewares.NewJWTAuth(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize authentication")
+	}
+	if !jwtAuth.Enabled() {
+		log.Warn().Msg("JWT authentication is disabled, set auth.hmac-secret or auth.jwks-file to accept bearer tokens")
+	}
 	// Setup echo
 	e := echo.New()
+	e.JSONSerializer = middlewares.JSONSerializer{}
+	e.IPExtractor = middlewares.IPExtractor(cfg)
+	e.Use(middlewares.RequestID)
+	e.Use(otelecho.Middleware(tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		// Probes and scrapes are not traced
+		switch c.Path() {
+		case "/healthz", "/readyz", "/metrics":
+			return true
+		}
+		return false
+	})))
+	e.Use(middlewares.Metrics)
+	e.Use(cors.Middleware())
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogURI:    true,
-		LogStatus: true,
+		LogURI:      true,
+		LogStatus:   true,
+		LogMethod:   true,
+		LogLatency:  true,
+		LogRemoteIP: true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
-			log.Logger.Info().
+			// Request ID and actor are set in the request context by middlewares
+			log.Ctx(c.Request().Context()).Info().
+				Str("method", v.Method).
 				Str("URI", v.URI).
 				Int("status", v.Status).
+				Dur("latency", v.Latency).
+				Str("remote_ip", v.RemoteIP).
+				Str("user", utils.ActorFromContext(c.Request().Context())).
 				Msg("request")
 
 			return nil
 		},
 	}))
 	pg := e.Group("/api1/public")
+	pg.Use(jwtAuth.Authenticate, middlewares.APIKey(apiKeyService), middlewares.Library(cfg), rateLimiter.Middleware)
+	idempotency := middlewares.Idempotency(idempotencyService)
+	// Reads are public, writes and personal endpoints need a token or an API key
+	authenticated := middlewares.RequireScope(models.ScopeRead)
+	write := middlewares.RequireScope(models.ScopeWrite)
+	admin := middlewares.RequireScope(models.ScopeAdmin)
 
 	// Endpoints
-	pg.POST("/songs", songController.CreateSong)
+	pg.POST("/songs", songController.CreateSong, write, idempotency)
 	pg.GET("/songs", songController.GetSongs)
+	pg.GET("/songs/trash", songController.GetTrash, write)
+	pg.POST("/songs/:id/restore", songController.RestoreSong, write)
+	pg.GET("/songs/:id/history", songController.GetSongHistory)
+	pg.POST("/songs/:id/revert", songController.RevertSong, write)
+	pg.POST("/songs/:id/tags", tagController.AddSongTags, write)
+	pg.DELETE("/songs/:id/tags/:tag", tagController.RemoveSongTag, write)
+	pg.GET("/tags", tagController.GetTags)
+	pg.PUT("/songs/:id/rating", ratingController.RateSong, authenticated)
+	pg.DELETE("/songs/:id/rating", ratingController.RemoveRating, authenticated)
+	pg.GET("/me/favorites", favoriteController.GetFavorites, authenticated)
+	pg.PUT("/me/favorites/:id", favoriteController.AddFavorite, authenticated)
+	pg.DELETE("/me/favorites/:id", favoriteController.RemoveFavorite, authenticated)
+	pg.POST("/playlists", playlistController.CreatePlaylist, write)
+	pg.GET("/playlists", playlistController.GetPlaylists)
+	pg.GET("/playlists/:id", playlistController.GetPlaylist)
+	pg.PUT("/playlists/:id", playlistController.PutPlaylist, write)
+	pg.DELETE("/playlists/:id", playlistController.DeletePlaylist, write)
+	pg.POST("/playlists/:id/entries", playlistController.AddEntry, write)
+	pg.PATCH("/playlists/:id/entries/:entryId", playlistController.MoveEntry, write)
+	pg.DELETE("/playlists/:id/entries/:entryId", playlistController.RemoveEntry, write)
+	pg.POST("/artists", artistController.CreateArtist, write)
+	pg.GET("/artists", artistController.GetArtists)
+	pg.GET("/artists/:id", artistController.GetArtist)
+	pg.PUT("/artists/:id", artistController.PutArtist, write)
+	pg.DELETE("/artists/:id", artistController.DeleteArtist, write)
+	pg.GET("/artists/:id/songs", artistController.GetArtistSongs)
+	pg.POST("/albums", albumController.CreateAlbum, write)
+	pg.GET("/albums", albumController.GetAlbums)
+	pg.GET("/albums/:id", albumController.GetAlbum)
+	pg.PUT("/albums/:id", albumController.PutAlbum, write)
+	pg.DELETE("/albums/:id", albumController.DeleteAlbum, write)
+	pg.POST("/albums/:id/tracks", albumController.AddTrack, write)
+	pg.DELETE("/albums/:id/tracks/:songId", albumController.RemoveTrack, write)
 	pg.GET("/songs/:id", songController.GetSong)
-	pg.PUT("/songs/:id", songController.PutSong)
-	pg.PATCH("/songs/:id", songController.PatchSong)
-	pg.DELETE("/songs/:id", songController.DeleteSong)
+	pg.PUT("/songs/:id", songController.PutSong, write, idempotency)
+	pg.PATCH("/songs/:id", songController.PatchSong, write)
+	pg.DELETE("/songs/:id", songController.DeleteSong, write)
+	pg.POST("/admin/api-keys", apiKeyController.IssueKey, admin)
+	pg.GET("/admin/api-keys", apiKeyController.GetKeys, admin)
+	pg.DELETE("/admin/api-keys/:id", apiKeyController.RevokeKey, admin)
+	// Probes and metrics
+	e.GET("/healthz", healthController.Live)
+	e.GET("/readyz", healthController.Ready)
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 	// Swagger
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	// Start server
-	e.Logger.Fatal(e.Start(":" + cfg.Server.Port))
+	e.Server.ReadTimeout = time.Duration(cfg.Server.ReadTimeout) * time.Second
+	e.Server.WriteTimeout = time.Duration(cfg.Server.WriteTimeout) * time.Second
+	e.Server.IdleTimeout = time.Duration(cfg.Server.IdleTimeout) * time.Second
+	e.Server.MaxHeaderBytes = cfg.Server.MaxHeaderBytes
+	go func() {
+		if err := e.Start(":" + cfg.Server.Port); err != nil && err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("Failed to start server")
+		}
+	}()
+
+	// Wait for a termination signal, the second one kills the process
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-signalCtx.Done()
+	stop()
+	log.Info().Msg("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+	// New requests are refused, in-flight requests are finished until the timeout
+	if err := e.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to finish in-flight requests")
+	}
+	stopJobs()
+	workers.Wait()
+	// Spans recorded before the server stopped are flushed to the exporter
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
+	if err := db.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close database connections")
+	}
+	log.Info().Msg("Server stopped")
 }
@@ -1,9 +1,15 @@
 package config
 
 import (
+	_ "embed"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -18,61 +24,259 @@ type Config struct {
 		Password string `yaml:"password"`
 	}
 	Server struct {
-		Port    string `yaml:"port"`
-		Timeout int    `yaml:"timeout"`
+		Port      string `yaml:"port"`
+		Timeout   int    `yaml:"timeout"`
+		StrictPut bool   `yaml:"strict-put"` // PUT doesn't create missing songs
+		// HTTP server limits, zero is no limit
+		ReadTimeout    int `yaml:"read-timeout"`     // seconds
+		WriteTimeout   int `yaml:"write-timeout"`    // seconds
+		IdleTimeout    int `yaml:"idle-timeout"`     // seconds, keep-alive connections are closed after that
+		MaxHeaderBytes int `yaml:"max-header-bytes"` // 1 MB if not set
+		// Seconds to finish in-flight requests on SIGINT or SIGTERM, remaining requests are dropped
+		ShutdownTimeout int `yaml:"shutdown-timeout"`
+		// CIDRs of reverse proxies, client IP is taken from X-Forwarded-For only for requests from them.
+		// Client IP is the address of the connection if not set, forwarding headers are ignored then
+		TrustedProxies []string `yaml:"trusted-proxies"`
 	}
 	ExternalAPI struct {
 		BaseURL string `yaml:"base-url"`
 		Retries int    `yaml:"retries"`
 		Timeout int    `yaml:"timeout"`
+		// Requests to the service over the limit or the number of concurrent requests wait in queue
+		RateLimit     Limit `yaml:"rate-limit"`
+		MaxConcurrent int   `yaml:"max-concurrent"` // 0 is no limit
 	} `yaml:"external-api"`
+	Idempotency struct {
+		TTL             int `yaml:"ttl"`              // hours
+		CleanupInterval int `yaml:"cleanup-interval"` // minutes
+		// Seconds a request keeps the key reserved until it completes, the key can be used again after that
+		// if the server crashed in the middle of the request
+		Lease int `yaml:"lease"`
+	}
+	Trash struct {
+		Retention     int `yaml:"retention"`      // hours, deleted songs are purged after that
+		PurgeInterval int `yaml:"purge-interval"` // minutes
+	}
+	Auth struct {
+		// JWT authentication is disabled if both are empty, only API keys are accepted then
+		HMACSecret string `yaml:"hmac-secret"` // HS256 key, HS256 tokens are rejected if empty
+		JWKSFile   string `yaml:"jwks-file"`   // RS256 public keys, RS256 tokens are rejected if empty
+		Issuer     string `yaml:"issuer"`      // Checked if set
+		Audience   string `yaml:"audience"`    // Checked if set
+	}
+	RBAC struct {
+		DefaultRole string            `yaml:"default-role"` // Role of tokens without role claim
+		Policies    map[string]string `yaml:"policies"`     // Minimum role by action, unlisted actions need admin
+	} `yaml:"rbac"`
+	Library struct {
+		Header string `yaml:"header"` // Header naming the library of the request
+		Domain string `yaml:"domain"` // Requests to <library>.<domain> work with the library if set
+	}
+	Health struct {
+		Timeout          int  `yaml:"timeout"`            // seconds, for every readiness check
+		ProbeExternalAPI bool `yaml:"probe-external-api"` // Music info API is a readiness check
+	}
+	RateLimit struct {
+		Default Limit            `yaml:"default"` // Limit of routes without their own
+		Routes  map[string]Limit `yaml:"routes"`  // By method and route path, like "POST /api1/public/songs"
+	} `yaml:"rate-limit"`
+	CORS struct {
+		AllowOrigins []string `yaml:"allow-origins"` // Origins of browser clients, * is any origin
+	} `yaml:"cors"`
+	Reload struct {
+		Interval int `yaml:"interval"` // seconds between checks of the config file for changes, 0 is SIGHUP only
+	}
+	Logging struct {
+		Level  string   `yaml:"level"`  // trace, debug, info, warn or error, info if not set
+		Format string   `yaml:"format"` // console or json
+		Caller bool     `yaml:"caller"` // Add file and line of the call to messages
+		Sample uint32   `yaml:"sample"` // Only 1 of N debug and trace messages is written, all if not set
+		Redact []string `yaml:"redact"` // Values of these fields are hidden, passwords in URLs are always hidden
+	}
+	Tracing struct {
+		Exporter    string   `yaml:"exporter"`     // none, otlp or file
+		Endpoint    string   `yaml:"endpoint"`     // host:port of OTLP/HTTP collector
+		Insecure    bool     `yaml:"insecure"`     // Send OTLP without TLS
+		File        string   `yaml:"file"`         // Spans are appended to it as JSON with file exporter
+		SampleRatio *float64 `yaml:"sample-ratio"` // Share of traces recorded, all if not set, none if 0
+	}
 }
 
+// Limit allows Rate requests per second to every client with bursts of up to Burst requests,
+// zero rate is no limit
+type Limit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// Config read from environment variables only, when there is no config file
+//
+//go:embed env.yaml
+var envConfig []byte
+
+const defaultPath = "./config.yaml"
+
+// ${VAR} or ${VAR:-default}
+var envVarRegexp = regexp.MustCompile(`\$\{(\w+)(:-([^}]*))?\}`)
+
+// Variables set from .env file, they follow changes of the file when config is reloaded
+var (
+	dotenvMu   sync.Mutex
+	dotenvVars = map[string]bool{}
+)
+
+// NewConfig reads config from the file at path, or from environment variables only if path is empty.
+// Variables from .env file are added to the environment if it exists, see loadDotenv. Config is validated
 func NewConfig(path string) (*Config, error) {
 	config := &Config{}
 
-	if err := godotenv.Load(".env"); err != nil {
+	if err := loadDotenv(".env"); err != nil {
 		return nil, err
 	}
 
-	file, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+	file := envConfig
+	if path != "" {
+		var err error
+		file, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	file, err = replaceEnvVars(file)
-	if err != nil {
+	// Variables are substituted in the parsed document, so their values can't change its structure
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(file, &document); err != nil {
 		return nil, err
 	}
-	err = yaml.Unmarshal(file, &config)
-	if err != nil {
+	if err := replaceEnvVars(&document); err != nil {
+		return nil, err
+	}
+	if document.Kind != 0 {
+		if err := document.Decode(config); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
 	return config, nil
 }
 
+// loadDotenv adds variables of the file at path to the environment. Variables set outside of the file
+// take precedence, variables set from the file before are updated or removed with it
+func loadDotenv(path string) error {
+	vars, err := godotenv.Read(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+	for name := range dotenvVars {
+		if _, ok := vars[name]; !ok {
+			os.Unsetenv(name)
+			delete(dotenvVars, name)
+		}
+	}
+	for name, value := range vars {
+		if _, set := os.LookupEnv(name); set && !dotenvVars[name] {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+		dotenvVars[name] = true
+	}
+	return nil
+}
+
+// ParseCLI returns path of the config file. Without -config flag ./config.yaml is used if it exists,
+// otherwise the path is empty and config is read from environment variables
 func ParseCLI() (string, error) {
 	var path string
 
-	flag.StringVar(&path, "config", "./config.yaml", "path to config file")
+	flag.StringVar(&path, "config", "", "path to config file, "+defaultPath+" if it exists")
 	flag.Parse()
 
+	if path == "" {
+		if _, err := os.Stat(defaultPath); err != nil {
+			return "", nil
+		}
+		path = defaultPath
+	}
 	s, err := os.Stat(path)
 	if err != nil {
 		return "", err
 	}
 	if s.IsDir() {
-		return "", err
+		return "", fmt.Errorf("config: %s is a directory", path)
 	}
 
 	return path, nil
 }
 
-func replaceEnvVars(input []byte) ([]byte, error) {
-	envVarRegexp := regexp.MustCompile(`\$\{(\w+)\}`)
-	return envVarRegexp.ReplaceAllFunc(input, func(match []byte) []byte {
-		key := string(match[2 : len(match)-1])
-		return []byte(os.Getenv(key))
-	}), nil
+// replaceEnvVars substitutes ${VAR} and ${VAR:-default} in scalars of the document with values of environment
+// variables. Plain scalars get the type of the new value, quoted ones stay strings. Sequence items which are
+// a single plain variable are split by commas, so a list can be set with one variable.
+// Default is used if the variable is unset or empty, unset variables without default are an error
+func replaceEnvVars(document *yaml.Node) error {
+	missing := []string{}
+	expand := func(value string) string {
+		return envVarRegexp.ReplaceAllStringFunc(value, func(match string) string {
+			groups := envVarRegexp.FindStringSubmatch(match)
+			key := groups[1]
+			value, ok := os.LookupEnv(key)
+			if groups[2] != "" {
+				if value == "" {
+					return groups[3]
+				}
+				return value
+			}
+			if !ok {
+				missing = append(missing, key)
+			}
+			return value
+		})
+	}
+	var replace func(node *yaml.Node)
+	replace = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if !envVarRegexp.MatchString(node.Value) {
+				return
+			}
+			node.Value = expand(node.Value)
+			if node.Style == 0 {
+				node.Tag = ""
+				// A value like null or ~ isn't meant to be empty
+				if node.ShortTag() == "!!null" && node.Value != "" {
+					node.Tag = "!!str"
+				}
+			}
+		case yaml.SequenceNode:
+			items := make([]*yaml.Node, 0, len(node.Content))
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode || item.Style != 0 || envVarRegexp.FindString(item.Value) != item.Value {
+					replace(item)
+					items = append(items, item)
+					continue
+				}
+				for _, value := range strings.Split(expand(item.Value), ",") {
+					if value = strings.TrimSpace(value); value != "" {
+						items = append(items, &yaml.Node{Kind: yaml.ScalarNode, Value: value, Line: item.Line, Column: item.Column})
+					}
+				}
+			}
+			node.Content = items
+		default:
+			for _, child := range node.Content {
+				replace(child)
+			}
+		}
+	}
+	replace(document)
+	if len(missing) > 0 {
+		return fmt.Errorf("config: environment variables %s are not set", strings.Join(missing, ", "))
+	}
+	return nil
 }
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setRequiredEnv sets variables without defaults in env.yaml
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "music")
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "music")
+	t.Setenv("BASE_URL", "http://localhost:8081")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+}
+
+// decode substitutes variables in the document and decodes it into out
+func decode(t *testing.T, document string, out interface{}) error {
+	t.Helper()
+	node := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(document), &node); err != nil {
+		t.Fatalf("Error parsing document: %v", err)
+	}
+	if err := replaceEnvVars(&node); err != nil {
+		return err
+	}
+	return node.Decode(out)
+}
+
+func TestReplaceEnvVars(t *testing.T) {
+	t.Setenv("TEST_PORT", "9090")
+	t.Setenv("TEST_EMPTY", "")
+	t.Setenv("TEST_PASSWORD", "p@ss: #word")
+	t.Setenv("TEST_NULL", "null")
+	t.Setenv("TEST_LIST", "https://a.example.com, https://b.example.com")
+	out := struct {
+		Port     int      `yaml:"port"`
+		Default  int      `yaml:"default"`
+		Empty    string   `yaml:"empty"`
+		Password string   `yaml:"password"`
+		Null     string   `yaml:"nothing"`
+		Quoted   string   `yaml:"quoted"`
+		URL      string   `yaml:"url"`
+		List     []string `yaml:"list"`
+		NoList   []string `yaml:"no-list"`
+	}{}
+	err := decode(t, `
+port: ${TEST_PORT:-8080}
+default: ${TEST_UNSET_WITH_DEFAULT:-8080}
+empty: ${TEST_EMPTY:-fallback}
+password: ${TEST_PASSWORD}
+nothing: ${TEST_NULL}
+quoted: "${TEST_PORT}"
+url: http://${TEST_PORT:-localhost}/api
+list:
+  - ${TEST_LIST:-}
+no-list:
+  - ${TEST_UNSET_LIST:-}
+`, &out)
+	if err != nil {
+		t.Fatalf("Error replacing variables: %v", err)
+	}
+	if out.Port != 9090 || out.Default != 8080 || out.Empty != "fallback" {
+		t.Fatalf("Unexpected values or defaults: %+v", out)
+	}
+	// Values with YAML syntax stay strings
+	if out.Password != "p@ss: #word" || out.Null != "null" || out.Quoted != "9090" {
+		t.Fatalf("Unexpected string values: %+v", out)
+	}
+	if out.URL != "http://9090/api" {
+		t.Fatalf("Expected variable inside a value to be replaced, got %q", out.URL)
+	}
+	if !slices.Equal(out.List, []string{"https://a.example.com", "https://b.example.com"}) || len(out.NoList) != 0 {
+		t.Fatalf("Unexpected lists: %q, %q", out.List, out.NoList)
+	}
+}
+
+func TestReplaceEnvVarsMissing(t *testing.T) {
+	out := map[string]string{}
+	err := decode(t, `
+a: ${TEST_UNSET_A}
+b: ${TEST_UNSET_B:-}
+c: ${TEST_UNSET_C}
+`, &out)
+	if err == nil {
+		t.Fatalf("Expected error for unset variables without default")
+	}
+	if !strings.Contains(err.Error(), "TEST_UNSET_A, TEST_UNSET_C") || strings.Contains(err.Error(), "TEST_UNSET_B") {
+		t.Fatalf("Expected unset variables without default in error, got %v", err)
+	}
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PASSWORD", "secret: with # yaml")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://a.example.com,https://b.example.com")
+	cfg, err := NewConfig("")
+	if err != nil {
+		t.Fatalf("Error reading config from environment: %v", err)
+	}
+	if cfg.Db.Password != "secret: with # yaml" || cfg.Db.Port != "5432" || cfg.Server.Timeout != 10 {
+		t.Fatalf("Unexpected config: %+v", cfg.Db)
+	}
+	if len(cfg.CORS.AllowOrigins) != 2 || len(cfg.Server.TrustedProxies) != 0 {
+		t.Fatalf("Unexpected lists: %q, %q", cfg.CORS.AllowOrigins, cfg.Server.TrustedProxies)
+	}
+}
+
+func TestNewConfigFile(t *testing.T) {
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: ${TEST_UNSET_PORT}\n"), 0o600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if _, err := NewConfig(path); err == nil || !strings.Contains(err.Error(), "TEST_UNSET_PORT") {
+		t.Fatalf("Expected unset variable error, got %v", err)
+	}
+}
+
+func TestLoadDotenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Error writing .env: %v", err)
+		}
+	}
+	t.Setenv("TEST_DOTENV_OUTSIDE", "outside")
+	t.Cleanup(func() {
+		for _, name := range []string{"TEST_DOTENV_CHANGED", "TEST_DOTENV_REMOVED"} {
+			os.Unsetenv(name)
+			delete(dotenvVars, name)
+		}
+	})
+
+	write("TEST_DOTENV_CHANGED=one\nTEST_DOTENV_REMOVED=removed\nTEST_DOTENV_OUTSIDE=file\n")
+	if err := loadDotenv(path); err != nil {
+		t.Fatalf("Error loading .env: %v", err)
+	}
+	if os.Getenv("TEST_DOTENV_CHANGED") != "one" || os.Getenv("TEST_DOTENV_REMOVED") != "removed" {
+		t.Fatalf("Expected variables of .env in the environment")
+	}
+	// Reloaded .env updates its variables and keeps variables set outside of it
+	write("TEST_DOTENV_CHANGED=two\nTEST_DOTENV_OUTSIDE=file\n")
+	if err := loadDotenv(path); err != nil {
+		t.Fatalf("Error reloading .env: %v", err)
+	}
+	if got := os.Getenv("TEST_DOTENV_CHANGED"); got != "two" {
+		t.Fatalf("Expected changed variable to be updated, got %q", got)
+	}
+	if _, ok := os.LookupEnv("TEST_DOTENV_REMOVED"); ok {
+		t.Fatalf("Expected variable removed from .env to be unset")
+	}
+	if got := os.Getenv("TEST_DOTENV_OUTSIDE"); got != "outside" {
+		t.Fatalf("Expected variable set outside of .env to take precedence, got %q", got)
+	}
+	if err := loadDotenv(filepath.Join(t.TempDir(), ".env")); err != nil {
+		t.Fatalf("Expected missing .env to be skipped, got %v", err)
+	}
+}
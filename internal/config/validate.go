@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks required settings and ranges of values, all problems are reported in one error
+func (c *Config) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Db.Host != "", "db.host is required")
+	check(isPort(c.Db.Port), "db.port should be a port number, got %q", c.Db.Port)
+	check(c.Db.Name != "", "db.name is required")
+	check(c.Db.User != "", "db.user is required")
+
+	check(isPort(c.Server.Port), "server.port should be a port number, got %q", c.Server.Port)
+	check(c.Server.Timeout > 0, "server.timeout should be positive, got %d", c.Server.Timeout)
+	check(c.Server.ReadTimeout >= 0, "server.read-timeout can't be negative, got %d", c.Server.ReadTimeout)
+	check(c.Server.WriteTimeout >= 0, "server.write-timeout can't be negative, got %d", c.Server.WriteTimeout)
+	check(c.Server.IdleTimeout >= 0, "server.idle-timeout can't be negative, got %d", c.Server.IdleTimeout)
+	check(c.Server.MaxHeaderBytes >= 0, "server.max-header-bytes can't be negative, got %d", c.Server.MaxHeaderBytes)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown-timeout should be positive, got %d", c.Server.ShutdownTimeout)
+	for _, cidr := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "server.trusted-proxies should be CIDRs, got %q", cidr)
+	}
+
+	u, err := url.Parse(c.ExternalAPI.BaseURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"external-api.base-url should be an http or https URL, got %q", c.ExternalAPI.BaseURL)
+	check(c.ExternalAPI.Timeout > 0, "external-api.timeout should be positive, got %d", c.ExternalAPI.Timeout)
+	check(c.ExternalAPI.Retries >= 0, "external-api.retries can't be negative, got %d", c.ExternalAPI.Retries)
+	check(validLimit(c.ExternalAPI.RateLimit),
+		"external-api.rate-limit should have non-negative rate and positive burst if rate is set")
+	check(c.ExternalAPI.MaxConcurrent >= 0,
+		"external-api.max-concurrent can't be negative, got %d", c.ExternalAPI.MaxConcurrent)
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl should be positive, got %d", c.Idempotency.TTL)
+	check(c.Idempotency.CleanupInterval > 0,
+		"idempotency.cleanup-interval should be positive, got %d", c.Idempotency.CleanupInterval)
+	check(c.Idempotency.Lease > c.Server.Timeout,
+		"idempotency.lease should be longer than server.timeout, got %d", c.Idempotency.Lease)
+	check(c.Trash.Retention > 0, "trash.retention should be positive, got %d", c.Trash.Retention)
+	check(c.Trash.PurgeInterval > 0, "trash.purge-interval should be positive, got %d", c.Trash.PurgeInterval)
+
+	check(validLimit(c.RateLimit.Default), "rate-limit.default should have non-negative rate and positive burst if rate is set")
+	for route, limit := range c.RateLimit.Routes {
+		check(validLimit(limit), "rate-limit.routes.%s should have non-negative rate and positive burst if rate is set", route)
+		method, path, ok := strings.Cut(route, " ")
+		check(ok && method == strings.ToUpper(method) && strings.HasPrefix(path, "/"),
+			"rate-limit.routes.%s should be a method and a route path", route)
+	}
+
+	// JWT authentication is disabled without keys, checks of its claims make no sense then
+	check(c.Auth.HMACSecret != "" || c.Auth.JWKSFile != "" || (c.Auth.Issuer == "" && c.Auth.Audience == ""),
+		"auth.issuer and auth.audience need auth.hmac-secret or auth.jwks-file")
+	check(c.Health.Timeout >= 0, "health.timeout can't be negative, got %d", c.Health.Timeout)
+
+	check(slices.Contains([]string{"", "console", "json"}, c.Logging.Format),
+		"logging.format should be console or json, got %q", c.Logging.Format)
+	check(slices.Contains([]string{"", "none", "otlp", "file"}, c.Tracing.Exporter),
+		"tracing.exporter should be none, otlp or file, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint is required with otlp exporter")
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file is required with file exporter")
+	if ratio := c.Tracing.SampleRatio; ratio != nil {
+		check(*ratio >= 0 && *ratio <= 1, "tracing.sample-ratio should be between 0 and 1, got %g", *ratio)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func isPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func validLimit(l Limit) bool {
+	return l.Rate == 0 || (l.Rate > 0 && l.Burst > 0)
+}
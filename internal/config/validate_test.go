@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	setRequiredEnv(t)
+	cfg, err := NewConfig("")
+	if err != nil {
+		t.Fatalf("Error reading config: %v", err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		problem string // empty if config is valid
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"missing db host", func(c *Config) { c.Db.Host = "" }, "db.host is required"},
+		{"bad port", func(c *Config) { c.Server.Port = "http" }, "server.port should be a port number"},
+		{"port out of range", func(c *Config) { c.Db.Port = "70000" }, "db.port should be a port number"},
+		{"bad base url", func(c *Config) { c.ExternalAPI.BaseURL = "localhost" }, "external-api.base-url"},
+		{"rate without burst", func(c *Config) { c.RateLimit.Default = Limit{Rate: 1} }, "rate-limit.default"},
+		{"no rate", func(c *Config) { c.RateLimit.Default = Limit{} }, ""},
+		{"bad route", func(c *Config) { c.RateLimit.Routes = map[string]Limit{"songs": {}} }, "rate-limit.routes.songs"},
+		{"lease within timeout", func(c *Config) { c.Idempotency.Lease = c.Server.Timeout }, "idempotency.lease"},
+		{"bad proxy", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.1"} }, "server.trusted-proxies"},
+		{"proxy", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8"} }, ""},
+		{"no jwt keys", func(c *Config) { c.Auth.HMACSecret, c.Auth.JWKSFile = "", "" }, ""},
+		{"issuer without jwt keys", func(c *Config) { c.Auth.HMACSecret, c.Auth.Issuer = "", "https://auth.example.com" }, "auth.issuer"},
+		{"bad exporter", func(c *Config) { c.Tracing.Exporter = "zipkin" }, "tracing.exporter"},
+		{"otlp without endpoint", func(c *Config) { c.Tracing.Exporter, c.Tracing.Endpoint = "otlp", "" }, "tracing.endpoint"},
+		{"sample ratio over 1", func(c *Config) { ratio := 1.5; c.Tracing.SampleRatio = &ratio }, "tracing.sample-ratio"},
+		{"no sampling", func(c *Config) { ratio := 0.0; c.Tracing.SampleRatio = &ratio }, ""},
+		{"sample ratio not set", func(c *Config) { c.Tracing.SampleRatio = nil }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.change(cfg)
+			err := cfg.Validate()
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("Expected config to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("Expected %q problem, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestValidateAllProblems(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Db.Host = ""
+	cfg.Server.Timeout = 0
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "db.host") || !strings.Contains(err.Error(), "server.timeout") {
+		t.Fatalf("Expected all problems in one error, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Watch reads config again on SIGHUP and, if interval is set, when the file at path is modified,
+// and passes it to apply. Invalid config is reported and skipped. Returns when ctx is done
+func Watch(ctx context.Context, path string, interval time.Duration, apply func(*Config)) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval > 0 && path != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modified := modTime(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			log.Info().Msg("SIGHUP received, reloading config")
+		case <-tick:
+			t := modTime(path)
+			if t.Equal(modified) {
+				continue
+			}
+			log.Info().Msgf("Config file %s changed, reloading config", path)
+		}
+		modified = modTime(path)
+		cfg, err := NewConfig(path)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to reload config, previous config is kept")
+			continue
+		}
+		apply(cfg)
+	}
+}
+
+// modTime returns modification time of the file, zero if it can't be read
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	s, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return s.ModTime()
+}
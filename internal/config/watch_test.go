@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeConfig writes env.yaml with the server port to path and moves its modification time forward
+func writeConfig(t *testing.T, path, port string, modified time.Time) {
+	t.Helper()
+	content := strings.Replace(string(envConfig), "port: ${PORT:-8080}", "port: "+port, 1)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatalf("Error changing modification time: %v", err)
+	}
+}
+
+// watch runs Watch on the config file until the test ends, applied configs are sent to the returned channel
+func watch(t *testing.T, path string, interval time.Duration) <-chan *Config {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	applied := make(chan *Config, 10)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, path, interval, func(cfg *Config) { applied <- cfg })
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// Watch reads modification time of the file when it starts
+	time.Sleep(50 * time.Millisecond)
+	return applied
+}
+
+// nextConfig returns the next applied config, nil if none is applied within a second
+func nextConfig(applied <-chan *Config) *Config {
+	select {
+	case cfg := <-applied:
+		return cfg
+	case <-time.After(time.Second):
+		return nil
+	}
+}
+
+func TestWatchModified(t *testing.T) {
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeConfig(t, path, "8081", start)
+	applied := watch(t, path, 10*time.Millisecond)
+
+	writeConfig(t, path, "8082", start.Add(time.Minute))
+	if cfg := nextConfig(applied); cfg == nil || cfg.Server.Port != "8082" {
+		t.Fatalf("Expected modified config to be applied, got %+v", cfg)
+	}
+	// Invalid config is skipped, the applied one stays in use
+	writeConfig(t, path, "http", start.Add(2*time.Minute))
+	if cfg := nextConfig(applied); cfg != nil {
+		t.Fatalf("Expected invalid config not to be applied, got port %s", cfg.Server.Port)
+	}
+	writeConfig(t, path, "8083", start.Add(3*time.Minute))
+	if cfg := nextConfig(applied); cfg == nil || cfg.Server.Port != "8083" {
+		t.Fatalf("Expected config fixed after an invalid one to be applied, got %+v", cfg)
+	}
+}
+
+func TestWatchUnchanged(t *testing.T) {
+	setRequiredEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "8081", time.Now().Add(-time.Hour))
+	applied := watch(t, path, 10*time.Millisecond)
+	if cfg := nextConfig(applied); cfg != nil {
+		t.Fatalf("Expected config not to be reloaded without changes")
+	}
+}
+
+func TestWatchSIGHUP(t *testing.T) {
+	setRequiredEnv(t)
+	// SIGHUP sent before Watch listens for it would stop the test
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	modified := time.Now().Add(-time.Hour)
+	writeConfig(t, path, "8081", modified)
+	// Without interval the file is read on SIGHUP only
+	applied := watch(t, path, 0)
+	// The file is changed without changing its modification time
+	writeConfig(t, path, "8082", modified)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatalf("Error sending SIGHUP: %v", err)
+		}
+		select {
+		case cfg := <-applied:
+			if cfg.Server.Port != "8082" {
+				t.Fatalf("Expected config to be read again on SIGHUP, got port %s", cfg.Server.Port)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected config to be reloaded on SIGHUP")
+		}
+	}
+}
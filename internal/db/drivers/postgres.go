@@ -1,7 +1,11 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"music-lib/internal/db/migrations"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -27,13 +31,67 @@ func Connect(URL string) (*sqlx.DB, error) {
 	return sqlx.NewDb(db, "postgres"), nil
 }
 
+var (
+	gooseOnce sync.Once
+	gooseErr  error
+	// Version of the latest embedded migration
+	latestVersion int64
+)
+
+// setupGoose configures goose globals once, they are not safe to change while migrations are checked
+func setupGoose() error {
+	gooseOnce.Do(func() {
+		goose.SetBaseFS(migrations.FS)
+		if gooseErr = goose.SetDialect("postgres"); gooseErr != nil {
+			return
+		}
+		embedded, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+		if err != nil {
+			gooseErr = err
+			return
+		}
+		latest, err := embedded.Last()
+		if err != nil {
+			gooseErr = err
+			return
+		}
+		latestVersion = latest.Version
+	})
+	return gooseErr
+}
+
 func runMigrations(db *sql.DB) error {
-	goose.SetDialect("postgres")
-	return goose.Up(db, "./internal/db/migrations")
+	if err := setupGoose(); err != nil {
+		return err
+	}
+	return goose.Up(db, ".")
 }
 
+// Down rolls back all migrations
 func Down(db *sqlx.DB) error {
-	goose.SetDialect("postgres")
+	if err := setupGoose(); err != nil {
+		return err
+	}
 	var sqlDB *sql.DB = db.DB
-	return goose.Down(sqlDB, "./internal/db/migrations")
+	return goose.DownTo(sqlDB, ".", 0)
+}
+
+// MigrationVersions returns version of the database and version of the latest embedded migration.
+// The database is only read, a version is applied if its latest record says so
+func MigrationVersions(ctx context.Context, db *sqlx.DB) (int64, int64, error) {
+	if err := setupGoose(); err != nil {
+		return 0, 0, err
+	}
+	query := fmt.Sprintf(`
+        SELECT COALESCE(MAX(version_id), 0) FROM (
+            SELECT DISTINCT ON (version_id) version_id, is_applied
+            FROM %s ORDER BY version_id, id DESC
+        ) v
+        WHERE is_applied
+        `, goose.TableName())
+	var current int64
+	if err := db.GetContext(ctx, &current, query); err != nil {
+		return 0, 0, err
+	}
+	return current, latestVersion, nil
 }
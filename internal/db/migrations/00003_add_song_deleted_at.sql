@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+ALTER TABLE song ADD COLUMN deleted_at TIMESTAMPTZ;
+-- Names must be unique only among songs that are not in trash
+ALTER TABLE song DROP CONSTRAINT song_name_artist_key;
+CREATE UNIQUE INDEX song_name_artist_key ON song (name, artist) WHERE deleted_at IS NULL;
+CREATE INDEX song_deleted_at_idx ON song (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DELETE FROM song WHERE deleted_at IS NOT NULL;
+DROP INDEX song_deleted_at_idx;
+DROP INDEX song_name_artist_key;
+ALTER TABLE song ADD CONSTRAINT song_name_artist_key UNIQUE (name, artist);
+ALTER TABLE song DROP COLUMN deleted_at;
+-- +goose StatementEnd
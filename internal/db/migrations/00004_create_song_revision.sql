@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- song_id has no foreign key, so history of purged songs is kept
+CREATE TABLE song_revision (
+    id SERIAL PRIMARY KEY,
+    song_id INT NOT NULL,
+    revision INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    snapshot JSONB NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (song_id, revision)
+);
+-- Baseline revision for existing songs
+INSERT INTO song_revision(song_id, revision, action, snapshot, actor, source)
+SELECT id, 1, 'create',
+       jsonb_strip_nulls(jsonb_build_object(
+           'id', id,
+           'song', name,
+           'group', artist,
+           'lyrics', lyrics,
+           'release_date', to_char(release_date, 'DD.MM.YYYY'),
+           'url', url,
+           'deleted_at', deleted_at
+       )),
+       'system', 'migration'
+FROM song;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE song_revision;
+-- +goose StatementEnd
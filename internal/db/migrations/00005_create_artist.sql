@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE artist (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    country CHAR(2)
+);
+CREATE INDEX artist_aliases_idx ON artist USING GIN (aliases);
+-- Move existing artists out of song, song.artist keeps canonical name of the artist
+INSERT INTO artist(name) SELECT DISTINCT artist FROM song;
+ALTER TABLE song ADD COLUMN artist_id INT REFERENCES artist(id);
+UPDATE song SET artist_id = artist.id FROM artist WHERE artist.name = song.artist;
+ALTER TABLE song ALTER COLUMN artist_id SET NOT NULL;
+CREATE INDEX song_artist_id_idx ON song (artist_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+ALTER TABLE song DROP COLUMN artist_id;
+DROP TABLE artist;
+-- +goose StatementEnd
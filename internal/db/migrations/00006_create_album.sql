@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE album (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    artist_id INT NOT NULL REFERENCES artist(id),
+    release_date DATE NOT NULL,
+    cover_url VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE (title, artist_id)
+);
+CREATE TABLE album_track (
+    album_id INT NOT NULL REFERENCES album(id) ON DELETE CASCADE,
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    disc_number INT NOT NULL DEFAULT 1 CHECK (disc_number > 0),
+    track_number INT NOT NULL CHECK (track_number > 0),
+    PRIMARY KEY (album_id, disc_number, track_number),
+    UNIQUE (album_id, song_id)
+);
+CREATE INDEX album_track_song_id_idx ON album_track (song_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE album_track;
+DROP TABLE album;
+-- +goose StatementEnd
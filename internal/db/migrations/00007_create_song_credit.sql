@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Credits in addition to the main artist of the song (song.artist_id), which is always the primary artist
+CREATE TABLE song_credit (
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    artist_id INT NOT NULL REFERENCES artist(id),
+    role VARCHAR(16) NOT NULL CHECK (role IN ('primary', 'featured', 'composer', 'lyricist', 'producer')),
+    PRIMARY KEY (song_id, artist_id, role)
+);
+CREATE INDEX song_credit_artist_id_idx ON song_credit (artist_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE song_credit;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Genres are part of the song and recorded in its history, tags are free-form labels managed separately
+CREATE TABLE genre (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+CREATE TABLE song_genre (
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    genre_id INT NOT NULL REFERENCES genre(id),
+    PRIMARY KEY (song_id, genre_id)
+);
+CREATE INDEX song_genre_genre_id_idx ON song_genre (genre_id);
+CREATE TABLE tag (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+CREATE TABLE song_tag (
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+CREATE INDEX song_tag_tag_id_idx ON song_tag (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE song_tag;
+DROP TABLE tag;
+DROP TABLE song_genre;
+DROP TABLE genre;
+-- +goose StatementEnd
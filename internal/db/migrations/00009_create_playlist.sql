@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+CREATE TABLE playlist (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner VARCHAR(255) NOT NULL,
+    visibility VARCHAR(16) NOT NULL DEFAULT 'private' CHECK (visibility IN ('public', 'private')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX playlist_owner_idx ON playlist (owner);
+-- The same song can be added more than once, entries are addressed by their own id.
+-- Positions are shifted with a single UPDATE, so uniqueness is checked at commit
+CREATE TABLE playlist_entry (
+    id SERIAL PRIMARY KEY,
+    playlist_id INT NOT NULL REFERENCES playlist(id) ON DELETE CASCADE,
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    position INT NOT NULL CHECK (position > 0),
+    added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT playlist_entry_position_key UNIQUE (playlist_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+CREATE INDEX playlist_entry_song_id_idx ON playlist_entry (song_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE playlist_entry;
+DROP TABLE playlist;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Songs of every library are kept apart, existing songs move to the default library
+ALTER TABLE song ADD COLUMN library VARCHAR(63) NOT NULL DEFAULT 'default';
+DROP INDEX song_name_artist_key;
+CREATE UNIQUE INDEX song_name_artist_key ON song (library, name, artist) WHERE deleted_at IS NULL;
+CREATE INDEX song_library_idx ON song (library);
+-- History stays in the library of the song after the song is purged
+ALTER TABLE song_revision ADD COLUMN library VARCHAR(63) NOT NULL DEFAULT 'default';
+-- Keys bound to a library can't access other libraries, NULL is any library
+ALTER TABLE api_key ADD COLUMN library VARCHAR(63);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DELETE FROM song WHERE library <> 'default';
+DELETE FROM song_revision WHERE library <> 'default';
+ALTER TABLE api_key DROP COLUMN library;
+ALTER TABLE song_revision DROP COLUMN library;
+DROP INDEX song_library_idx;
+DROP INDEX song_name_artist_key;
+CREATE UNIQUE INDEX song_name_artist_key ON song (name, artist) WHERE deleted_at IS NULL;
+ALTER TABLE song DROP COLUMN library;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Users are subjects of their tokens or API keys
+CREATE TABLE song_favorite (
+    user_id VARCHAR(255) NOT NULL,
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, song_id)
+);
+CREATE INDEX song_favorite_song_id_idx ON song_favorite (song_id);
+CREATE TABLE song_rating (
+    user_id VARCHAR(255) NOT NULL,
+    song_id INT NOT NULL REFERENCES song(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, song_id)
+);
+CREATE INDEX song_rating_song_id_idx ON song_rating (song_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE song_rating;
+DROP TABLE song_favorite;
+-- +goose StatementEnd
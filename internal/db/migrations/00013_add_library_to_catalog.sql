@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Artists, albums and playlists are kept apart by library like songs, existing ones move to the default library
+ALTER TABLE artist ADD COLUMN library VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE artist DROP CONSTRAINT artist_name_key;
+-- Artists of songs in other libraries are copied to those libraries
+INSERT INTO artist(library, name, aliases, country)
+SELECT DISTINCT used.library, artist.name, artist.aliases, artist.country
+FROM artist JOIN (
+    SELECT library, artist_id FROM song
+    UNION
+    SELECT song.library, sc.artist_id FROM song_credit sc JOIN song ON song.id = sc.song_id
+) used ON used.artist_id = artist.id
+WHERE used.library <> 'default';
+UPDATE song SET artist_id = copy.id
+FROM artist original, artist copy
+WHERE song.artist_id = original.id AND song.library <> 'default'
+    AND copy.library = song.library AND copy.name = original.name;
+UPDATE song_credit sc SET artist_id = copy.id
+FROM song, artist original, artist copy
+WHERE song.id = sc.song_id AND sc.artist_id = original.id AND song.library <> 'default'
+    AND copy.library = song.library AND copy.name = original.name;
+ALTER TABLE artist ADD CONSTRAINT artist_library_name_key UNIQUE (library, name);
+ALTER TABLE album ADD COLUMN library VARCHAR(63) NOT NULL DEFAULT 'default';
+CREATE INDEX album_library_idx ON album (library);
+ALTER TABLE playlist ADD COLUMN library VARCHAR(63) NOT NULL DEFAULT 'default';
+CREATE INDEX playlist_library_idx ON playlist (library);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DELETE FROM playlist WHERE library <> 'default';
+DELETE FROM album WHERE library <> 'default';
+DROP INDEX playlist_library_idx;
+ALTER TABLE playlist DROP COLUMN library;
+DROP INDEX album_library_idx;
+ALTER TABLE album DROP COLUMN library;
+-- Songs go back to artists of the default library with the same name
+UPDATE song SET artist_id = original.id
+FROM artist copy, artist original
+WHERE song.artist_id = copy.id AND copy.library <> 'default'
+    AND original.library = 'default' AND original.name = copy.name;
+UPDATE song_credit sc SET artist_id = original.id
+FROM artist copy, artist original
+WHERE sc.artist_id = copy.id AND copy.library <> 'default'
+    AND original.library = 'default' AND original.name = copy.name;
+DELETE FROM artist copy
+WHERE copy.library <> 'default'
+    AND EXISTS (SELECT 1 FROM artist original WHERE original.library = 'default' AND original.name = copy.name);
+-- Names of artists left in other libraries stay unique with the library in them
+UPDATE artist SET name = name || ' (' || library || ')' WHERE library <> 'default';
+ALTER TABLE artist DROP CONSTRAINT artist_library_name_key;
+ALTER TABLE artist DROP COLUMN library;
+ALTER TABLE artist ADD CONSTRAINT artist_name_key UNIQUE (name);
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- Keys are kept per actor and library, stored keys can't be attributed to them and are dropped
+DELETE FROM idempotency_key;
+ALTER TABLE idempotency_key DROP CONSTRAINT idempotency_key_pkey;
+ALTER TABLE idempotency_key ADD COLUMN actor VARCHAR(255) NOT NULL;
+ALTER TABLE idempotency_key ADD COLUMN library VARCHAR(63) NOT NULL;
+-- Token of the request holding the reservation, only it completes or releases the key
+ALTER TABLE idempotency_key ADD COLUMN token CHAR(32) NOT NULL;
+ALTER TABLE idempotency_key ADD PRIMARY KEY (actor, library, key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DELETE FROM idempotency_key;
+ALTER TABLE idempotency_key DROP CONSTRAINT idempotency_key_pkey;
+ALTER TABLE idempotency_key DROP COLUMN token;
+ALTER TABLE idempotency_key DROP COLUMN library;
+ALTER TABLE idempotency_key DROP COLUMN actor;
+ALTER TABLE idempotency_key ADD PRIMARY KEY (key);
+-- +goose StatementEnd
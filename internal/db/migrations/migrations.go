@@ -0,0 +1,7 @@
+// Package migrations embeds goose migrations, so the binary doesn't depend on the working directory
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,22 @@
+package models
+
+import "music-lib/internal/utils"
+
+type Album struct {
+	ID          *int             `db:"id" json:"id" example:"1"`
+	Library     string           `db:"library" json:"-"` // Taken from the request, albums of other libraries are not visible
+	Title       string           `db:"title" json:"title" example:"Abbey Road"`
+	Artist      string           `db:"artist" json:"group" example:"The Beatles"` // Canonical name of the artist
+	ArtistID    *int             `db:"artist_id" json:"artist_id" example:"1"`
+	ReleaseDate utils.CustomDate `db:"release_date" json:"release_date" format:"string" example:"26.09.1969"`
+	CoverURL    string           `db:"cover_url" json:"cover_url" example:"https://example.com/cover.jpg"`
+	Tracks      []AlbumTrack     `db:"-" json:"tracks,omitempty"` // Ordered by disc and track number
+}
+
+type AlbumTrack struct {
+	AlbumID     int   `db:"album_id" json:"-"`
+	SongID      int   `db:"song_id" json:"song_id" example:"1"`
+	DiscNumber  int   `db:"disc_number" json:"disc_number" example:"1"`
+	TrackNumber int   `db:"track_number" json:"track_number" example:"1"`
+	Song        *Song `db:"-" json:"song,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scopes granted to API keys and tokens
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+type APIKey struct {
+	ID         *int           `db:"id" json:"id" example:"1"`
+	Name       string         `db:"name" json:"name" example:"nightly-import"`
+	Prefix     string         `db:"prefix" json:"prefix" example:"mlk_3f9a"` // Start of the key to tell keys apart
+	KeyHash    string         `db:"key_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes" swaggertype:"array,string" example:"read,write"`
+	Library    *string        `db:"library" json:"library" example:"team-a"` // Any library if null
+	CreatedAt  time.Time      `db:"created_at" json:"created_at" example:"2024-10-01T12:00:00Z"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at" example:"2025-10-01T12:00:00Z"` // Never expires if null
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at" example:"2024-10-02T12:00:00Z"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at" example:"2024-10-03T12:00:00Z"`
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IssuedAPIKey is a newly issued key with its secret value, which is not stored
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"mlk_3f9a..."`
+}
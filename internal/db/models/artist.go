@@ -0,0 +1,11 @@
+package models
+
+import "github.com/lib/pq"
+
+type Artist struct {
+	ID      *int           `db:"id" json:"id" example:"1"`
+	Library string         `db:"library" json:"-"` // Taken from the request, artists of other libraries are not visible
+	Name    string         `db:"name" json:"name" example:"The Beatles"`
+	Aliases pq.StringArray `db:"aliases" json:"aliases" swaggertype:"array,string" example:"Beatles"` // Alternative names, songs can refer to the artist by them
+	Country *string        `db:"country" json:"country" example:"GB"`                                 // ISO 3166-1 alpha-2 code
+}
@@ -0,0 +1,31 @@
+package models
+
+import "music-lib/internal/utils"
+
+// Roles of artists credited on a song
+const (
+	RolePrimary  = "primary"
+	RoleFeatured = "featured"
+	RoleComposer = "composer"
+	RoleLyricist = "lyricist"
+	RoleProducer = "producer"
+)
+
+type Credit struct {
+	SongID   int    `db:"song_id" json:"-"`
+	ArtistID int    `db:"artist_id" json:"artist_id" example:"2"`
+	Artist   string `db:"artist" json:"group" example:"Featured artist name"`
+	Role     string `db:"role" json:"role" example:"featured"`
+}
+
+// CreditsFromRequest converts requested credits, nil stays nil so existing credits are kept
+func CreditsFromRequest(reqs []utils.CreditRequest) []Credit {
+	if reqs == nil {
+		return nil
+	}
+	credits := make([]Credit, 0, len(reqs))
+	for _, req := range reqs {
+		credits = append(credits, Credit{Artist: req.Group, Role: req.Role})
+	}
+	return credits
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Favorite struct {
+	User      string    `db:"user_id" json:"-"`
+	SongID    int       `db:"song_id" json:"song_id" example:"1"`
+	CreatedAt time.Time `db:"created_at" json:"favorited_at" example:"2024-10-01T12:00:00Z"`
+	Song      *Song     `db:"-" json:"song,omitempty"`
+}
+
+type Rating struct {
+	User      string    `db:"user_id" json:"-"`
+	SongID    int       `db:"song_id" json:"song_id" example:"1"`
+	Rating    int       `db:"rating" json:"rating" example:"4"` // From 1 to 5
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at" example:"2024-10-01T12:00:00Z"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the outcome of a request sent with an Idempotency-Key header.
+// Keys are kept per actor and library. StatusCode is nil while the original request is still being processed,
+// Token identifies the request holding the key then
+type IdempotencyKey struct {
+	Actor        string    `db:"actor"`
+	Library      string    `db:"library"`
+	Key          string    `db:"key"`
+	Token        string    `db:"token"`
+	Fingerprint  string    `db:"fingerprint"`
+	StatusCode   *int      `db:"status_code"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
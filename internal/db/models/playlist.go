@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Visibility of a playlist, private playlists are seen only by their owner
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+type Playlist struct {
+	ID          *int            `db:"id" json:"id" example:"1"`
+	Library     string          `db:"library" json:"-"` // Taken from the request, playlists of other libraries are not visible
+	Name        string          `db:"name" json:"name" example:"Road trip"`
+	Description string          `db:"description" json:"description" example:"Songs for a long drive"`
+	Owner       string          `db:"owner" json:"owner" example:"john"`
+	Visibility  string          `db:"visibility" json:"visibility" example:"private"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at" example:"2024-10-01T12:00:00Z"`
+	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at" example:"2024-10-01T12:00:00Z"`
+	Entries     []PlaylistEntry `db:"-" json:"entries,omitempty"` // Ordered by position
+}
+
+type PlaylistEntry struct {
+	ID         int       `db:"id" json:"id" example:"1"`
+	PlaylistID int       `db:"playlist_id" json:"-"`
+	SongID     int       `db:"song_id" json:"song_id" example:"1"`
+	Position   int       `db:"position" json:"position" example:"1"` // Starts from 1
+	AddedAt    time.Time `db:"added_at" json:"added_at" example:"2024-10-01T12:00:00Z"`
+	Song       *Song     `db:"-" json:"song,omitempty"`
+}
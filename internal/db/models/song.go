@@ -2,13 +2,22 @@ package models
 
 import (
 	"music-lib/internal/utils"
+	"time"
 )
 
 type Song struct {
 	ID          *int             `db:"id" json:"id" example:"1"`
+	Library     string           `db:"library" json:"-"` // Taken from the request, songs of other libraries are not visible
 	Name        string           `db:"name" json:"song" example:"Song name"`
-	Artist      string           `db:"artist" json:"group" example:"Artist or group name"`
+	Artist      string           `db:"artist" json:"group" example:"Artist or group name"` // Canonical name of the artist
+	ArtistID    *int             `db:"artist_id" json:"artist_id" example:"1"`
 	Lyrics      string           `db:"lyrics" json:"lyrics" example:"Lyrics of the song"`
 	ReleaseDate utils.CustomDate `db:"release_date" json:"release_date" format:"string" example:"02.01.2006"`
 	URL         string           `db:"url" json:"url" example:"https://www.youtube.com/watch?v=12345"`
+	DeletedAt   *time.Time       `db:"deleted_at" json:"deleted_at,omitempty" example:"2024-10-01T12:00:00Z"` // Set for songs in trash
+	Credits     []Credit         `db:"-" json:"credits,omitempty"`                                            // Main artist is always the first primary credit
+	Genres      []string         `db:"-" json:"genres,omitempty" example:"rock"`
+	Tags        []string         `db:"-" json:"tags,omitempty" example:"chill"`     // Managed with tag endpoints, not part of song history
+	RatingAvg   float64          `db:"-" json:"rating_avg,omitempty" example:"4.5"` // Average user rating, not part of song history
+	RatingCount int              `db:"-" json:"rating_count,omitempty" example:"2"`
 }
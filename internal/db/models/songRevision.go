@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// SongRevision is a snapshot of a song taken after every change
+type SongRevision struct {
+	ID        int            `db:"id" json:"-"`
+	SongID    int            `db:"song_id" json:"song_id" example:"1"`
+	Library   string         `db:"library" json:"-"`
+	Revision  int            `db:"revision" json:"revision" example:"2"`
+	Action    string         `db:"action" json:"action" example:"update"` // create, update, delete or restore
+	Snapshot  types.JSONText `db:"snapshot" json:"snapshot" swaggertype:"object"`
+	Actor     string         `db:"actor" json:"actor" example:"anonymous"`
+	Source    string         `db:"source" json:"source" example:"api"` // api or revert
+	CreatedAt time.Time      `db:"created_at" json:"created_at" example:"2024-10-01T12:00:00Z"`
+	Changes   []FieldChange  `db:"-" json:"changes"` // Difference from the previous revision
+}
+
+type FieldChange struct {
+	Field string      `json:"field" example:"lyrics"`
+	Old   interface{} `json:"old" swaggertype:"string"`
+	New   interface{} `json:"new" swaggertype:"string"`
+}
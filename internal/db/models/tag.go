@@ -0,0 +1,27 @@
+package models
+
+import "strings"
+
+// TagCount is a tag with the number of songs labeled with it, songs in trash are not counted
+type TagCount struct {
+	Name  string `db:"name" json:"name" example:"chill"`
+	Count int    `db:"count" json:"count" example:"3"`
+}
+
+// NormalizeLabels trims and lowercases genres or tags and drops empty and repeated ones, nil stays nil
+func NormalizeLabels(labels []string) []string {
+	if labels == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	return normalized
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type IAlbumRepo interface {
+	GetAll(ctx context.Context, offset int, limit int) ([]models.Album, error)
+	GetById(ctx context.Context, id int) (*models.Album, error)
+	Save(ctx context.Context, album *models.Album) error
+	Delete(ctx context.Context, id int) error
+	GetTracks(ctx context.Context, id int) ([]models.AlbumTrack, error)
+	AddTrack(ctx context.Context, track *models.AlbumTrack) error
+	RemoveTrack(ctx context.Context, id, songID int) error
+}
+
+type AlbumRepository struct {
+	db *sqlx.DB
+}
+
+func NewAlbumRepository(db *sqlx.DB) IAlbumRepo {
+	return &AlbumRepository{db}
+}
+
+const albumSelect = `SELECT album.*, artist.name AS artist FROM album JOIN artist ON artist.id = album.artist_id`
+
+func (r *AlbumRepository) GetAll(ctx context.Context, offset, limit int) ([]models.Album, error) {
+	defer metrics.ObserveQuery("album", "GetAll", time.Now())
+	albums := []models.Album{}
+	query := albumSelect + ` WHERE album.library=$1 ORDER BY album.id ASC LIMIT $2 OFFSET $3`
+	span := startQuery(ctx, "AlbumRepository.GetAll", query)
+	err := r.db.SelectContext(ctx, &albums, query, utils.LibraryFromContext(ctx), limit, offset)
+	endQuery(span, int64(len(albums)), err)
+	if err != nil {
+		return nil, err
+	}
+	return albums, nil
+}
+
+func (r *AlbumRepository) GetById(ctx context.Context, id int) (*models.Album, error) {
+	defer metrics.ObserveQuery("album", "GetById", time.Now())
+	album := models.Album{}
+	query := albumSelect + ` WHERE album.id=$1 AND album.library=$2`
+	span := startQuery(ctx, "AlbumRepository.GetById", query)
+	err := r.db.GetContext(ctx, &album, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, 1, err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: album with id %d doesn't exist", id)
+		}
+		return nil, err
+	}
+	return &album, nil
+}
+
+// Save saves an album to db if id not set, otherwise updates the existing album.
+// Artist is resolved by name or alias and created if it doesn't exist.
+// Albums belong to the library of the request, only albums of that library can be updated
+func (r *AlbumRepository) Save(ctx context.Context, album *models.Album) error {
+	defer metrics.ObserveQuery("album", "Save", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	artistID, artist, err := resolveArtist(ctx, tx, album.Artist)
+	if err != nil {
+		return err
+	}
+	album.ArtistID = &artistID
+	album.Artist = artist
+	album.Library = utils.LibraryFromContext(ctx)
+
+	if album.ID != nil {
+		// Update album
+		query := `
+            UPDATE album
+            SET title=$1, artist_id=$2, release_date=$3, cover_url=$4
+            WHERE id=$5 AND library=$6
+            `
+		span := startQuery(ctx, "AlbumRepository.Save", query)
+		res, err := tx.ExecContext(ctx, query,
+			album.Title, album.ArtistID, album.ReleaseDate, album.CoverURL, *album.ID, album.Library)
+		endQuery(span, affected(res), err)
+		if err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+				// Unique violation
+				return fmt.Errorf("duplicate error: album %s of artist %s already exists", album.Title, album.Artist)
+			}
+			return err
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count != 1 {
+			return fmt.Errorf("not found error: album with id %d not found", *album.ID)
+		}
+	} else {
+		// Create new album
+		query := `
+            INSERT INTO
+            album(library, title, artist_id, release_date, cover_url)
+            VALUES($1, $2, $3, $4, $5)
+            RETURNING id
+            `
+		span := startQuery(ctx, "AlbumRepository.Save", query)
+		err := tx.QueryRowxContext(ctx, query, album.Library, album.Title, album.ArtistID, album.ReleaseDate, album.CoverURL).
+			Scan(&album.ID)
+		endQuery(span, 1, err)
+		if err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+				// Unique violation
+				return fmt.Errorf("duplicate error: album %s of artist %s already exists", album.Title, album.Artist)
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *AlbumRepository) Delete(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("album", "Delete", time.Now())
+	query := `DELETE FROM album WHERE id = $1 AND library = $2`
+	span := startQuery(ctx, "AlbumRepository.Delete", query)
+	res, err := r.db.ExecContext(ctx, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, affected(res), err)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: album with id %d not found", id)
+	}
+	return nil
+}
+
+// GetTracks returns tracks of the album ordered by disc and track number, songs are not loaded
+func (r *AlbumRepository) GetTracks(ctx context.Context, id int) ([]models.AlbumTrack, error) {
+	defer metrics.ObserveQuery("album", "GetTracks", time.Now())
+	tracks := []models.AlbumTrack{}
+	query := `
+        SELECT album_track.* FROM album_track JOIN album ON album.id = album_track.album_id
+        WHERE album_id=$1 AND album.library=$2
+        ORDER BY disc_number ASC, track_number ASC
+        `
+	span := startQuery(ctx, "AlbumRepository.GetTracks", query)
+	err := r.db.SelectContext(ctx, &tracks, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, int64(len(tracks)), err)
+	if err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// AddTrack puts the song on the album at the given disc and track number,
+// the album and the song must be in the library of the request
+func (r *AlbumRepository) AddTrack(ctx context.Context, track *models.AlbumTrack) error {
+	defer metrics.ObserveQuery("album", "AddTrack", time.Now())
+	query := `
+        INSERT INTO
+        album_track(album_id, song_id, disc_number, track_number)
+        SELECT album.id, song.id, $3, $4 FROM album, song
+        WHERE album.id=$1 AND album.library=$5 AND song.id=$2 AND song.library=$5 AND song.deleted_at IS NULL
+        `
+	span := startQuery(ctx, "AlbumRepository.AddTrack", query)
+	res, err := r.db.ExecContext(ctx, query,
+		track.AlbumID, track.SongID, track.DiscNumber, track.TrackNumber, utils.LibraryFromContext(ctx))
+	endQuery(span, affected(res), err)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok {
+			switch err.Code {
+			case "23505":
+				// Unique violation
+				return fmt.Errorf("duplicate error: track %d of disc %d is taken or song %d is already on the album",
+					track.TrackNumber, track.DiscNumber, track.SongID)
+			case "23503":
+				// Foreign key violation
+				return fmt.Errorf("not found error: album with id %d doesn't exist", track.AlbumID)
+			}
+		}
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		if _, err := r.GetById(ctx, track.AlbumID); err != nil {
+			return err
+		}
+		return fmt.Errorf("not found error: song with id %d doesn't exist", track.SongID)
+	}
+	return nil
+}
+
+func (r *AlbumRepository) RemoveTrack(ctx context.Context, id, songID int) error {
+	defer metrics.ObserveQuery("album", "RemoveTrack", time.Now())
+	query := `
+        DELETE FROM album_track
+        WHERE album_id=$1 AND song_id=$2 AND album_id IN (SELECT id FROM album WHERE library=$3)
+        `
+	span := startQuery(ctx, "AlbumRepository.RemoveTrack", query)
+	res, err := r.db.ExecContext(ctx, query, id, songID, utils.LibraryFromContext(ctx))
+	endQuery(span, affected(res), err)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: song with id %d is not on album %d", songID, id)
+	}
+	return nil
+}
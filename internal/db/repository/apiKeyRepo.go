@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+type IAPIKeyRepo interface {
+	GetAll(ctx context.Context, library string) ([]models.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	Save(ctx context.Context, key *models.APIKey) error
+	Revoke(ctx context.Context, id int, library string) error
+	Touch(ctx context.Context, id int) error
+}
+
+type APIKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) IAPIKeyRepo {
+	return &APIKeyRepository{db}
+}
+
+// GetAll returns keys including revoked and expired ones, newest first,
+// only keys bound to the library are returned unless library is empty
+func (r *APIKeyRepository) GetAll(ctx context.Context, library string) ([]models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "GetAll", time.Now())
+	keys := []models.APIKey{}
+	query := `SELECT * FROM api_key WHERE $1 = '' OR library=$1 ORDER BY id DESC`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &keys, query, library)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	defer metrics.ObserveQuery("api_key", "GetByHash", time.Now())
+	key := models.APIKey{}
+	query := `SELECT * FROM api_key WHERE key_hash=$1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.GetContext(ctx, &key, query, hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: api key doesn't exist")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Save stores a new key
+func (r *APIKeyRepository) Save(ctx context.Context, key *models.APIKey) error {
+	defer metrics.ObserveQuery("api_key", "Save", time.Now())
+	query := `
+        INSERT INTO
+        api_key(name, prefix, key_hash, scopes, library, expires_at)
+        VALUES($1, $2, $3, $4, $5, $6)
+        RETURNING *
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	return r.db.GetContext(ctx, key, query, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.Library, key.ExpiresAt)
+}
+
+// Revoke marks the key as revoked, revoking a key twice is not an error.
+// Keys not bound to the library are not found unless library is empty
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int, library string) error {
+	defer metrics.ObserveQuery("api_key", "Revoke", time.Now())
+	query := `
+        UPDATE api_key SET revoked_at = COALESCE(revoked_at, NOW())
+        WHERE id=$1 AND ($2 = '' OR library=$2)
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query, id, library)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: api key with id %d not found", id)
+	}
+	return nil
+}
+
+// Touch sets last usage time of the key, it is updated at most once a minute to spare writes
+func (r *APIKeyRepository) Touch(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("api_key", "Touch", time.Now())
+	query := `
+        UPDATE api_key SET last_used_at = NOW()
+        WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+type IArtistRepo interface {
+	GetAll(ctx context.Context, offset int, limit int) ([]models.Artist, error)
+	GetById(ctx context.Context, id int) (*models.Artist, error)
+	Save(ctx context.Context, artist *models.Artist) error
+	Delete(ctx context.Context, id int) error
+}
+
+type ArtistRepository struct {
+	db *sqlx.DB
+}
+
+func NewArtistRepository(db *sqlx.DB) IArtistRepo {
+	return &ArtistRepository{db}
+}
+
+func (r *ArtistRepository) GetAll(ctx context.Context, offset, limit int) ([]models.Artist, error) {
+	defer metrics.ObserveQuery("artist", "GetAll", time.Now())
+	artists := []models.Artist{}
+	query := `SELECT * FROM artist WHERE library=$1 ORDER BY name ASC LIMIT $2 OFFSET $3`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &artists, query, utils.LibraryFromContext(ctx), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return artists, nil
+}
+
+func (r *ArtistRepository) GetById(ctx context.Context, id int) (*models.Artist, error) {
+	defer metrics.ObserveQuery("artist", "GetById", time.Now())
+	artist := models.Artist{}
+	query := `SELECT * FROM artist WHERE id=$1 AND library=$2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.GetContext(ctx, &artist, query, id, utils.LibraryFromContext(ctx))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: artist with id %d doesn't exist", id)
+		}
+		return nil, err
+	}
+	return &artist, nil
+}
+
+// Save saves an artist to db if id not set, otherwise updates the existing artist.
+// When the artist is renamed, its songs are updated and the old name is kept as an alias.
+// Artists belong to the library of the request, only artists of that library can be updated
+func (r *ArtistRepository) Save(ctx context.Context, artist *models.Artist) error {
+	defer metrics.ObserveQuery("artist", "Save", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if artist.Aliases == nil {
+		artist.Aliases = pq.StringArray{}
+	}
+	artist.Library = utils.LibraryFromContext(ctx)
+	id := 0
+	if artist.ID != nil {
+		id = *artist.ID
+		old := models.Artist{}
+		query := `SELECT * FROM artist WHERE id=$1 AND library=$2 FOR UPDATE`
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if err := tx.GetContext(ctx, &old, query, id, artist.Library); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("not found error: artist with id %d doesn't exist", id)
+			}
+			return err
+		}
+		if old.Name != artist.Name && !containsName(artist.Aliases, old.Name) {
+			artist.Aliases = append(artist.Aliases, old.Name)
+		}
+	}
+	// Name and aliases must not refer to another artist of the library
+	names := append(pq.StringArray{artist.Name}, artist.Aliases...)
+	query := `SELECT name FROM artist WHERE id <> $1 AND library=$2 AND (name = ANY($3) OR aliases && $3) LIMIT 1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	var other string
+	err = tx.GetContext(ctx, &other, query, id, artist.Library, names)
+	if err == nil {
+		return fmt.Errorf("duplicate error: name or alias of artist %s is used by artist %s", artist.Name, other)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	if artist.ID != nil {
+		// Update artist
+		query := `UPDATE artist SET name=$1, aliases=$2, country=$3 WHERE id=$4`
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if _, err := tx.ExecContext(ctx, query, artist.Name, artist.Aliases, artist.Country, id); err != nil {
+			return err
+		}
+		// Keep canonical name in songs of the artist, they are all in the library of the artist
+		query = `UPDATE song SET artist=$1 WHERE artist_id=$2 AND library=$3 AND artist<>$1 RETURNING id`
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		songIDs := []int{}
+		if err := tx.SelectContext(ctx, &songIDs, query, artist.Name, id, artist.Library); err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+				// Unique violation
+				return fmt.Errorf("duplicate error: artist %s already has a song with the same name", artist.Name)
+			}
+			return err
+		}
+		for _, songID := range songIDs {
+			if err := recordRevision(ctx, tx, songID, ActionUpdate); err != nil {
+				return err
+			}
+		}
+	} else {
+		// Create new artist
+		query := `
+            INSERT INTO
+            artist(library, name, aliases, country)
+            VALUES($1, $2, $3, $4)
+            RETURNING id
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		err := tx.QueryRowxContext(ctx, query, artist.Library, artist.Name, artist.Aliases, artist.Country).Scan(&artist.ID)
+		if err != nil {
+			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+				// Unique violation
+				return fmt.Errorf("duplicate error: artist with name %s already exists", artist.Name)
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ArtistRepository) Delete(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("artist", "Delete", time.Now())
+	query := `DELETE FROM artist WHERE id = $1 AND library = $2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query, id, utils.LibraryFromContext(ctx))
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23503" {
+			// Foreign key violation
+			return fmt.Errorf("conflict error: artist with id %d has songs or albums", id)
+		}
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: artist with id %d not found", id)
+	}
+	return nil
+}
+
+// resolveArtist finds the artist by name or alias in the library of the request and returns its id and
+// canonical name. Artist is created if it doesn't exist
+func resolveArtist(ctx context.Context, tx *sqlx.Tx, name string) (int, string, error) {
+	var artist models.Artist
+	library := utils.LibraryFromContext(ctx)
+	query := `
+        SELECT * FROM artist
+        WHERE library=$1 AND (name=$2 OR $2 = ANY(aliases))
+        ORDER BY name=$2 DESC, id ASC
+        LIMIT 1
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := tx.GetContext(ctx, &artist, query, library, name)
+	if err == nil {
+		return *artist.ID, artist.Name, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", err
+	}
+	query = `
+        INSERT INTO
+        artist(library, name)
+        VALUES($1, $2)
+        ON CONFLICT (library, name) DO UPDATE SET name=EXCLUDED.name
+        RETURNING *
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &artist, query, library, name); err != nil {
+		return 0, "", err
+	}
+	return *artist.ID, artist.Name, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
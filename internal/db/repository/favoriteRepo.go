@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+type IFavoriteRepo interface {
+	GetByUser(ctx context.Context, user string, offset, limit int) ([]models.Favorite, error)
+	Save(ctx context.Context, favorite *models.Favorite) error
+	Delete(ctx context.Context, user string, songID int) error
+}
+
+type FavoriteRepository struct {
+	db *sqlx.DB
+}
+
+func NewFavoriteRepository(db *sqlx.DB) IFavoriteRepo {
+	return &FavoriteRepository{db}
+}
+
+// GetByUser returns favorites of the user among songs of the library, most recent first.
+// Songs in trash are skipped
+func (r *FavoriteRepository) GetByUser(ctx context.Context, user string, offset, limit int) ([]models.Favorite, error) {
+	defer metrics.ObserveQuery("favorite", "GetByUser", time.Now())
+	favorites := []models.Favorite{}
+	query := `
+        SELECT f.* FROM song_favorite f
+        JOIN song ON song.id = f.song_id AND song.library=$2 AND song.deleted_at IS NULL
+        WHERE f.user_id=$1
+        ORDER BY f.created_at DESC, f.song_id ASC
+        LIMIT $3 OFFSET $4
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &favorites, query, user, utils.LibraryFromContext(ctx), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+// Save adds the song of the library to favorites of the user, adding it again keeps the original time
+func (r *FavoriteRepository) Save(ctx context.Context, favorite *models.Favorite) error {
+	defer metrics.ObserveQuery("favorite", "Save", time.Now())
+	query := `
+        INSERT INTO
+        song_favorite(user_id, song_id)
+        SELECT $1, id FROM song WHERE id=$2 AND library=$3 AND deleted_at IS NULL
+        ON CONFLICT (user_id, song_id) DO UPDATE SET created_at=song_favorite.created_at
+        RETURNING created_at
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.QueryRowxContext(ctx, query, favorite.User, favorite.SongID, utils.LibraryFromContext(ctx)).
+		Scan(&favorite.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: song with id %d doesn't exist", favorite.SongID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *FavoriteRepository) Delete(ctx context.Context, user string, songID int) error {
+	defer metrics.ObserveQuery("favorite", "Delete", time.Now())
+	query := `
+        DELETE FROM song_favorite
+        WHERE user_id=$1 AND song_id=$2 AND song_id IN (SELECT id FROM song WHERE library=$3)
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query, user, songID, utils.LibraryFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: song with id %d is not a favorite of %s", songID, user)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/db/drivers"
+	"music-lib/internal/metrics"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IHealthRepo interface {
+	Ping(ctx context.Context) error
+	CheckMigrations(ctx context.Context) error
+}
+
+type HealthRepository struct {
+	db *sqlx.DB
+}
+
+func NewHealthRepository(db *sqlx.DB) IHealthRepo {
+	return &HealthRepository{db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	defer metrics.ObserveQuery("health", "Ping", time.Now())
+	return r.db.PingContext(ctx)
+}
+
+// CheckMigrations returns error if the database is not migrated to the latest embedded migration
+func (r *HealthRepository) CheckMigrations(ctx context.Context) error {
+	defer metrics.ObserveQuery("health", "CheckMigrations", time.Now())
+	current, latest, err := drivers.MigrationVersions(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	if current != latest {
+		return fmt.Errorf("database is at version %d, migrations expect %d", current, latest)
+	}
+	return nil
+}
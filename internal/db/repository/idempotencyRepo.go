@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+type IIdempotencyRepo interface {
+	Reserve(ctx context.Context, key, token, fingerprint string, expiresAt time.Time) (bool, error)
+	Get(ctx context.Context, key string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time) error
+	Delete(ctx context.Context, key, token string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// IdempotencyRepository works with keys of the actor and library from the request context,
+// see utils.WithActor and utils.WithLibrary
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) IIdempotencyRepo {
+	return &IdempotencyRepository{db}
+}
+
+// Reserve inserts a pending record for the key held by the request with the token.
+// An expired record with the same key is replaced. Returns false if a live record for the key already exists
+func (r *IdempotencyRepository) Reserve(
+	ctx context.Context, key, token, fingerprint string, expiresAt time.Time,
+) (bool, error) {
+	defer metrics.ObserveQuery("idempotency", "Reserve", time.Now())
+	query := `
+        INSERT INTO
+        idempotency_key(actor, library, key, token, fingerprint, expires_at)
+        VALUES($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (actor, library, key) DO UPDATE
+        SET token=EXCLUDED.token, fingerprint=EXCLUDED.fingerprint, status_code=NULL, response_body=NULL,
+            created_at=NOW(), expires_at=EXCLUDED.expires_at
+        WHERE idempotency_key.expires_at <= NOW()
+        RETURNING key
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	var reserved string
+	err := r.db.QueryRowxContext(ctx, query,
+		utils.ActorFromContext(ctx), utils.LibraryFromContext(ctx), key, token, fingerprint, expiresAt).Scan(&reserved)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	defer metrics.ObserveQuery("idempotency", "Get", time.Now())
+	record := models.IdempotencyKey{}
+	query := `SELECT * FROM idempotency_key WHERE actor=$1 AND library=$2 AND key=$3 AND expires_at > NOW()`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.GetContext(ctx, &record, query, utils.ActorFromContext(ctx), utils.LibraryFromContext(ctx), key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: idempotency key %s doesn't exist", key)
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response of the request holding the key with the token, the response is kept until expiresAt.
+// Nothing is stored if the lease of the request expired and the key was reserved by another request
+func (r *IdempotencyRepository) Complete(
+	ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time,
+) error {
+	defer metrics.ObserveQuery("idempotency", "Complete", time.Now())
+	query := `
+        UPDATE idempotency_key SET status_code=$1, response_body=$2, expires_at=$3
+        WHERE actor=$4 AND library=$5 AND key=$6 AND token=$7 AND status_code IS NULL
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, err := r.db.ExecContext(ctx, query,
+		status, body, expiresAt, utils.ActorFromContext(ctx), utils.LibraryFromContext(ctx), key, token)
+	return err
+}
+
+// Delete releases the key held by the request with the token, keys reserved by other requests are kept
+func (r *IdempotencyRepository) Delete(ctx context.Context, key, token string) error {
+	defer metrics.ObserveQuery("idempotency", "Delete", time.Now())
+	query := `
+        DELETE FROM idempotency_key
+        WHERE actor=$1 AND library=$2 AND key=$3 AND token=$4 AND status_code IS NULL
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, err := r.db.ExecContext(ctx, query, utils.ActorFromContext(ctx), utils.LibraryFromContext(ctx), key, token)
+	return err
+}
+
+// DeleteExpired removes expired keys and returns the number of deleted rows
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	defer metrics.ObserveQuery("idempotency", "DeleteExpired", time.Now())
+	query := `DELETE FROM idempotency_key WHERE expires_at <= NOW()`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+type IPlaylistRepo interface {
+	GetVisible(ctx context.Context, owner string, offset int, limit int) ([]models.Playlist, error)
+	GetById(ctx context.Context, id int) (*models.Playlist, error)
+	Save(ctx context.Context, playlist *models.Playlist) error
+	Delete(ctx context.Context, id int) error
+	GetEntries(ctx context.Context, id int) ([]models.PlaylistEntry, error)
+	InsertEntry(ctx context.Context, entry *models.PlaylistEntry) error
+	MoveEntry(ctx context.Context, entry *models.PlaylistEntry) error
+	RemoveEntry(ctx context.Context, id, entryID int) error
+}
+
+type PlaylistRepository struct {
+	db *sqlx.DB
+}
+
+func NewPlaylistRepository(db *sqlx.DB) IPlaylistRepo {
+	return &PlaylistRepository{db}
+}
+
+// GetVisible returns public playlists and private playlists of the owner in the library of the request,
+// most recently updated first
+func (r *PlaylistRepository) GetVisible(ctx context.Context, owner string, offset, limit int) ([]models.Playlist, error) {
+	defer metrics.ObserveQuery("playlist", "GetVisible", time.Now())
+	playlists := []models.Playlist{}
+	query := `
+        SELECT * FROM playlist
+        WHERE library = $1 AND (visibility = 'public' OR owner = $2)
+        ORDER BY updated_at DESC, id DESC
+        LIMIT $3 OFFSET $4
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &playlists, query, utils.LibraryFromContext(ctx), owner, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func (r *PlaylistRepository) GetById(ctx context.Context, id int) (*models.Playlist, error) {
+	defer metrics.ObserveQuery("playlist", "GetById", time.Now())
+	playlist := models.Playlist{}
+	query := `SELECT * FROM playlist WHERE id=$1 AND library=$2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.GetContext(ctx, &playlist, query, id, utils.LibraryFromContext(ctx))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: playlist with id %d doesn't exist", id)
+		}
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// Save saves a playlist to db if id not set, otherwise updates name, description and visibility.
+// Owner and library of a playlist can't be changed
+func (r *PlaylistRepository) Save(ctx context.Context, playlist *models.Playlist) error {
+	defer metrics.ObserveQuery("playlist", "Save", time.Now())
+	if playlist.ID != nil {
+		// Update playlist
+		query := `
+            UPDATE playlist
+            SET name=$1, description=$2, visibility=$3, updated_at=NOW()
+            WHERE id=$4 AND library=$5
+            RETURNING *
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		err := r.db.GetContext(ctx, playlist, query,
+			playlist.Name, playlist.Description, playlist.Visibility, *playlist.ID, utils.LibraryFromContext(ctx))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("not found error: playlist with id %d not found", *playlist.ID)
+			}
+			return err
+		}
+		return nil
+	}
+	// Create new playlist
+	query := `
+        INSERT INTO
+        playlist(library, name, description, owner, visibility)
+        VALUES($1, $2, $3, $4, $5)
+        RETURNING *
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	return r.db.GetContext(ctx, playlist, query,
+		utils.LibraryFromContext(ctx), playlist.Name, playlist.Description, playlist.Owner, playlist.Visibility)
+}
+
+func (r *PlaylistRepository) Delete(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("playlist", "Delete", time.Now())
+	query := `DELETE FROM playlist WHERE id = $1 AND library = $2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query, id, utils.LibraryFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: playlist with id %d not found", id)
+	}
+	return nil
+}
+
+// GetEntries returns entries of the playlist ordered by position, songs are not loaded
+func (r *PlaylistRepository) GetEntries(ctx context.Context, id int) ([]models.PlaylistEntry, error) {
+	defer metrics.ObserveQuery("playlist", "GetEntries", time.Now())
+	entries := []models.PlaylistEntry{}
+	query := `
+        SELECT playlist_entry.* FROM playlist_entry JOIN playlist ON playlist.id = playlist_entry.playlist_id
+        WHERE playlist_id=$1 AND playlist.library=$2
+        ORDER BY position ASC
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &entries, query, id, utils.LibraryFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// InsertEntry puts the song at entry.Position, entries from that position on are moved down.
+// Position 0 or past the end appends the song, entry.Position is set to the actual position
+func (r *PlaylistRepository) InsertEntry(ctx context.Context, entry *models.PlaylistEntry) error {
+	defer metrics.ObserveQuery("playlist", "InsertEntry", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	last, err := lockPlaylist(ctx, tx, entry.PlaylistID)
+	if err != nil {
+		return err
+	}
+	if entry.Position == 0 || entry.Position > last+1 {
+		entry.Position = last + 1
+	}
+	query := `UPDATE playlist_entry SET position = position + 1 WHERE playlist_id=$1 AND position >= $2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, entry.PlaylistID, entry.Position); err != nil {
+		return err
+	}
+	query = `
+        INSERT INTO
+        playlist_entry(playlist_id, song_id, position)
+        SELECT $1, id, $3 FROM song WHERE id=$2 AND library=$4 AND deleted_at IS NULL
+        RETURNING id, added_at
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err = tx.QueryRowxContext(ctx, query,
+		entry.PlaylistID, entry.SongID, entry.Position, utils.LibraryFromContext(ctx)).
+		Scan(&entry.ID, &entry.AddedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: song with id %d doesn't exist", entry.SongID)
+		}
+		return err
+	}
+	if err := touchPlaylist(ctx, tx, entry.PlaylistID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MoveEntry moves the entry to entry.Position shifting entries in between, position past the end moves it last.
+// entry.Position is set to the actual position
+func (r *PlaylistRepository) MoveEntry(ctx context.Context, entry *models.PlaylistEntry) error {
+	defer metrics.ObserveQuery("playlist", "MoveEntry", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	last, err := lockPlaylist(ctx, tx, entry.PlaylistID)
+	if err != nil {
+		return err
+	}
+	if entry.Position > last {
+		entry.Position = last
+	}
+	var from int
+	query := `SELECT position FROM playlist_entry WHERE id=$1 AND playlist_id=$2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &from, query, entry.ID, entry.PlaylistID); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: entry with id %d is not in playlist %d", entry.ID, entry.PlaylistID)
+		}
+		return err
+	}
+	if from < entry.Position {
+		// Entries after the old position up to the new one move up
+		query = `
+            UPDATE playlist_entry SET position = position - 1
+            WHERE playlist_id=$1 AND position > $2 AND position <= $3
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if _, err := tx.ExecContext(ctx, query, entry.PlaylistID, from, entry.Position); err != nil {
+			return err
+		}
+	} else if from > entry.Position {
+		// Entries from the new position up to the old one move down
+		query = `
+            UPDATE playlist_entry SET position = position + 1
+            WHERE playlist_id=$1 AND position >= $2 AND position < $3
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if _, err := tx.ExecContext(ctx, query, entry.PlaylistID, entry.Position, from); err != nil {
+			return err
+		}
+	}
+	query = `UPDATE playlist_entry SET position=$1 WHERE id=$2 RETURNING *`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, entry, query, entry.Position, entry.ID); err != nil {
+		return err
+	}
+	if err := touchPlaylist(ctx, tx, entry.PlaylistID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveEntry removes the entry from the playlist, entries after it move up
+func (r *PlaylistRepository) RemoveEntry(ctx context.Context, id, entryID int) error {
+	defer metrics.ObserveQuery("playlist", "RemoveEntry", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPlaylist(ctx, tx, id); err != nil {
+		return err
+	}
+	var position int
+	query := `DELETE FROM playlist_entry WHERE id=$1 AND playlist_id=$2 RETURNING position`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &position, query, entryID, id); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: entry with id %d is not in playlist %d", entryID, id)
+		}
+		return err
+	}
+	query = `UPDATE playlist_entry SET position = position - 1 WHERE playlist_id=$1 AND position > $2`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, id, position); err != nil {
+		return err
+	}
+	if err := touchPlaylist(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockPlaylist locks the playlist row until the end of tx, so concurrent edits of its entries
+// are applied one after another, and returns the last position in the playlist.
+// Playlists of other libraries are not found
+func lockPlaylist(ctx context.Context, tx *sqlx.Tx, id int) (int, error) {
+	var locked int
+	query := `SELECT id FROM playlist WHERE id=$1 AND library=$2 FOR UPDATE`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &locked, query, id, utils.LibraryFromContext(ctx)); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("not found error: playlist with id %d doesn't exist", id)
+		}
+		return 0, err
+	}
+	var last int
+	query = `SELECT COALESCE(MAX(position), 0) FROM playlist_entry WHERE playlist_id=$1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &last, query, id); err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
+func touchPlaylist(ctx context.Context, tx *sqlx.Tx, id int) error {
+	query := `UPDATE playlist SET updated_at=NOW() WHERE id=$1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
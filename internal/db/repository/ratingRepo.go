@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+type IRatingRepo interface {
+	Save(ctx context.Context, rating *models.Rating) error
+	Delete(ctx context.Context, user string, songID int) error
+}
+
+type RatingRepository struct {
+	db *sqlx.DB
+}
+
+func NewRatingRepository(db *sqlx.DB) IRatingRepo {
+	return &RatingRepository{db}
+}
+
+// Save rates the song of the library on behalf of the user, previous rating of the user is replaced
+func (r *RatingRepository) Save(ctx context.Context, rating *models.Rating) error {
+	defer metrics.ObserveQuery("rating", "Save", time.Now())
+	query := `
+        INSERT INTO
+        song_rating(user_id, song_id, rating)
+        SELECT $1, id, $3 FROM song WHERE id=$2 AND library=$4 AND deleted_at IS NULL
+        ON CONFLICT (user_id, song_id) DO UPDATE SET rating=EXCLUDED.rating, updated_at=NOW()
+        RETURNING updated_at
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.QueryRowxContext(ctx, query,
+		rating.User, rating.SongID, rating.Rating, utils.LibraryFromContext(ctx)).
+		Scan(&rating.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: song with id %d doesn't exist", rating.SongID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *RatingRepository) Delete(ctx context.Context, user string, songID int) error {
+	defer metrics.ObserveQuery("rating", "Delete", time.Now())
+	query := `
+        DELETE FROM song_rating
+        WHERE user_id=$1 AND song_id=$2 AND song_id IN (SELECT id FROM song WHERE library=$3)
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	res, err := r.db.ExecContext(ctx, query, user, songID, utils.LibraryFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: song with id %d is not rated by %s", songID, user)
+	}
+	return nil
+}
+
+// loadRatings sets average rating and the number of ratings of the songs
+func loadRatings(ctx context.Context, q sqlx.QueryerContext, songs []models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, *song.ID)
+	}
+	rows := []struct {
+		SongID int     `db:"song_id"`
+		Avg    float64 `db:"avg"`
+		Count  int     `db:"count"`
+	}{}
+	query := `
+        SELECT song_id, ROUND(AVG(rating), 2)::float8 AS avg, COUNT(*) AS count
+        FROM song_rating
+        WHERE song_id = ANY($1)
+        GROUP BY song_id
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := sqlx.SelectContext(ctx, q, &rows, query, pq.Array(ids)); err != nil {
+		return err
+	}
+	bySong := make(map[int]int, len(rows))
+	for i, row := range rows {
+		bySong[row.SongID] = i
+	}
+	for i := range songs {
+		if j, ok := bySong[*songs[i].ID]; ok {
+			songs[i].RatingAvg = rows[j].Avg
+			songs[i].RatingCount = rows[j].Count
+		}
+	}
+	return nil
+}
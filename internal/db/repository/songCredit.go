@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// loadCredits sets credits of the songs, main artist of a song is its first primary credit
+func loadCredits(ctx context.Context, q sqlx.QueryerContext, songs []models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, *song.ID)
+	}
+	credits := []models.Credit{}
+	query := `
+        SELECT sc.song_id, sc.artist_id, artist.name AS artist, sc.role
+        FROM song_credit sc JOIN artist ON artist.id = sc.artist_id
+        WHERE sc.song_id = ANY($1)
+        ORDER BY array_position(ARRAY['primary', 'featured', 'composer', 'lyricist', 'producer'], sc.role::text),
+                 artist.name ASC
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := sqlx.SelectContext(ctx, q, &credits, query, pq.Array(ids)); err != nil {
+		return err
+	}
+	creditsBySong := make(map[int][]models.Credit, len(songs))
+	for _, credit := range credits {
+		creditsBySong[credit.SongID] = append(creditsBySong[credit.SongID], credit)
+	}
+	for i := range songs {
+		main := models.Credit{SongID: *songs[i].ID, Artist: songs[i].Artist, Role: models.RolePrimary}
+		if songs[i].ArtistID != nil {
+			main.ArtistID = *songs[i].ArtistID
+		}
+		songs[i].Credits = append([]models.Credit{main}, creditsBySong[*songs[i].ID]...)
+	}
+	return nil
+}
+
+// saveCredits replaces credits of the song with song.Credits, stored credits are kept if they are nil.
+// Primary credit of the main artist is implied and not stored
+func saveCredits(ctx context.Context, tx *sqlx.Tx, song *models.Song) error {
+	if song.Credits == nil {
+		return nil
+	}
+	query := `DELETE FROM song_credit WHERE song_id=$1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, *song.ID); err != nil {
+		return err
+	}
+	for i := range song.Credits {
+		credit := &song.Credits[i]
+		artistID, artist, err := resolveArtist(ctx, tx, credit.Artist)
+		if err != nil {
+			return err
+		}
+		credit.SongID = *song.ID
+		credit.ArtistID = artistID
+		credit.Artist = artist
+		if artistID == *song.ArtistID && credit.Role == models.RolePrimary {
+			continue
+		}
+		query := `
+            INSERT INTO
+            song_credit(song_id, artist_id, role)
+            VALUES($1, $2, $3)
+            ON CONFLICT DO NOTHING
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if _, err := tx.ExecContext(ctx, query, credit.SongID, credit.ArtistID, credit.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,31 +2,56 @@ package repository
 
 import (
 	"fmt"
+	"music-lib/internal/db/models"
 	"music-lib/internal/utils"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tag match modes
+const (
+	TagMatchAny = "any" // Songs with at least one of the tags
+	TagMatchAll = "all" // Songs with every tag
 )
 
+// Orders of filtered songs by sort query parameter, "-" sorts in descending order.
+// Songs without ratings are the last whatever the order
+var songSorts = map[string]string{
+	"id":      `id ASC`,
+	"-id":     `id DESC`,
+	"rating":  `(SELECT AVG(rating) FROM song_rating WHERE song_id = song.id) ASC NULLS LAST, id ASC`,
+	"-rating": `(SELECT AVG(rating) FROM song_rating WHERE song_id = song.id) DESC NULLS LAST, id ASC`,
+}
+
 type SongFilter struct {
-	Name   string           `db:"name"`
-	Artist string           `db:"artist"`
-	After  utils.CustomDate `db:"after"`  // Song released after this date, inclusive
-	Before utils.CustomDate `db:"before"` // Song released before this date, inclusive
+	Library  string           `db:"library"` // Set by the repository from the request context
+	Name     string           `db:"name"`
+	Artist   string           `db:"artist"` // Name or alias of the artist
+	ArtistID int              `db:"artist_id"`
+	After    utils.CustomDate `db:"after"`  // Song released after this date, inclusive
+	Before   utils.CustomDate `db:"before"` // Song released before this date, inclusive
+	Genre    string           `db:"genre"`
+	Tags     pq.StringArray   `db:"tags"`
+	TagMatch string           `db:"-"` // TagMatchAny or TagMatchAll, any by default
+	Sort     string           `db:"-"` // Key of songSorts, id by default
 }
 
 // ParseQuery parses query parameters and returns SongFilter, page and limit
-// query parameters: group, song, after, before, page, limit
+// query parameters: group, song, after, before, genre, tag, tag_match, sort, page, limit
+// tag can be repeated or comma separated, tag_match is any or all, sort is id, -id, rating or -rating
 // page and limit are used for pagination, default values are 1 and 10 respectively
 func ParseQuery(query url.Values) (*SongFilter, int, int, error) {
-	f := SongFilter{}
-	// Default values
-	page := 1
-	limit := 10
-    var err error
+	f := SongFilter{TagMatch: TagMatchAny, Sort: "id"}
+	pagination := url.Values{}
 	// Parse query parameters
 	for key, value := range query {
 		switch key {
+		case "page", "limit":
+			pagination[key] = value
 		case "group":
 			f.Artist = value[0]
 		case "song":
@@ -43,19 +68,56 @@ func ParseQuery(query url.Values) (*SongFilter, int, int, error) {
 				return nil, 0, 0, fmt.Errorf("invalid date format: %v, must be dd.mm.yyyy", value[0])
 			}
 			f.Before = utils.CustomDate(t)
+		case "genre":
+			f.Genre = strings.ToLower(strings.TrimSpace(value[0]))
+		case "tag":
+			var tags []string
+			for _, v := range value {
+				tags = append(tags, strings.Split(v, ",")...)
+			}
+			f.Tags = models.NormalizeLabels(tags)
+		case "tag_match":
+			if value[0] != TagMatchAny && value[0] != TagMatchAll {
+				return nil, 0, 0, fmt.Errorf("invalid tag match: %v, must be any or all", value[0])
+			}
+			f.TagMatch = value[0]
+		case "sort":
+			if _, ok := songSorts[value[0]]; !ok {
+				return nil, 0, 0, fmt.Errorf("invalid sort: %v, must be id, -id, rating or -rating", value[0])
+			}
+			f.Sort = value[0]
+		default:
+			return nil, 0, 0, fmt.Errorf("invalid query parameter: %s", key)
+		}
+	}
+	page, limit, err := ParsePagination(pagination)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return &f, page, limit, nil
+}
+
+// ParsePagination parses page and limit query parameters, any other parameter is an error
+// default values are 1 and 10 respectively
+func ParsePagination(query url.Values) (int, int, error) {
+	page := 1
+	limit := 10
+	var err error
+	for key, value := range query {
+		switch key {
 		case "page":
 			page, err = strconv.Atoi(value[0])
 			if err != nil || page < 1 {
-				return nil, 0, 0, fmt.Errorf("invalid page number: %v", value[0])
+				return 0, 0, fmt.Errorf("invalid page number: %v", value[0])
 			}
 		case "limit":
-            limit, err = strconv.Atoi(value[0])
+			limit, err = strconv.Atoi(value[0])
 			if err != nil || limit < 1 {
-				return nil, 0, 0, fmt.Errorf("invalid limit: %v", value[0])
+				return 0, 0, fmt.Errorf("invalid limit: %v", value[0])
 			}
 		default:
-			return nil, 0, 0, fmt.Errorf("invalid query parameter: %s", key)
+			return 0, 0, fmt.Errorf("invalid query parameter: %s", key)
 		}
 	}
-	return &f, page, limit, nil
+	return page, limit, nil
 }
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// loadDetails sets credits, genres, tags and ratings of the songs
+func loadDetails(ctx context.Context, q sqlx.QueryerContext, songs []models.Song) error {
+	if err := loadCredits(ctx, q, songs); err != nil {
+		return err
+	}
+	if err := loadGenres(ctx, q, songs); err != nil {
+		return err
+	}
+	if err := loadTags(ctx, q, songs); err != nil {
+		return err
+	}
+	return loadRatings(ctx, q, songs)
+}
+
+// reloadDetails loads stored credits, genres, tags and ratings into the saved song
+func reloadDetails(ctx context.Context, tx *sqlx.Tx, song *models.Song) error {
+	songs := []models.Song{*song}
+	if err := loadDetails(ctx, tx, songs); err != nil {
+		return err
+	}
+	*song = songs[0]
+	return nil
+}
+
+func loadGenres(ctx context.Context, q sqlx.QueryerContext, songs []models.Song) error {
+	query := `
+        SELECT sg.song_id, genre.name
+        FROM song_genre sg JOIN genre ON genre.id = sg.genre_id
+        WHERE sg.song_id = ANY($1)
+        ORDER BY genre.name ASC
+        `
+	genres, err := loadLabels(ctx, q, songs, query)
+	if err != nil {
+		return err
+	}
+	for i := range songs {
+		songs[i].Genres = genres[*songs[i].ID]
+	}
+	return nil
+}
+
+func loadTags(ctx context.Context, q sqlx.QueryerContext, songs []models.Song) error {
+	query := `
+        SELECT st.song_id, tag.name
+        FROM song_tag st JOIN tag ON tag.id = st.tag_id
+        WHERE st.song_id = ANY($1)
+        ORDER BY tag.name ASC
+        `
+	tags, err := loadLabels(ctx, q, songs, query)
+	if err != nil {
+		return err
+	}
+	for i := range songs {
+		songs[i].Tags = tags[*songs[i].ID]
+	}
+	return nil
+}
+
+// loadLabels runs query selecting song_id and name for ids of the songs and groups names by song
+func loadLabels(ctx context.Context, q sqlx.QueryerContext, songs []models.Song, query string) (map[int][]string, error) {
+	labels := make(map[int][]string, len(songs))
+	if len(songs) == 0 {
+		return labels, nil
+	}
+	ids := make([]int, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, *song.ID)
+	}
+	rows := []struct {
+		SongID int    `db:"song_id"`
+		Name   string `db:"name"`
+	}{}
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := sqlx.SelectContext(ctx, q, &rows, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		labels[row.SongID] = append(labels[row.SongID], row.Name)
+	}
+	return labels, nil
+}
+
+// saveGenres replaces genres of the song with song.Genres, stored genres are kept if they are nil.
+// Unknown genres are created
+func saveGenres(ctx context.Context, tx *sqlx.Tx, song *models.Song) error {
+	if song.Genres == nil {
+		return nil
+	}
+	query := `DELETE FROM song_genre WHERE song_id=$1`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, *song.ID); err != nil {
+		return err
+	}
+	if len(song.Genres) == 0 {
+		return nil
+	}
+	query = `
+        INSERT INTO genre(name) SELECT unnest($1::text[])
+        ON CONFLICT (name) DO NOTHING
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, pq.Array(song.Genres)); err != nil {
+		return err
+	}
+	query = `
+        INSERT INTO song_genre(song_id, genre_id)
+        SELECT $1, id FROM genre WHERE name = ANY($2)
+        ON CONFLICT DO NOTHING
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, err := tx.ExecContext(ctx, query, *song.ID, pq.Array(song.Genres))
+	return err
+}
@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
 	"music-lib/internal/utils"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -16,10 +19,28 @@ type ISongRepo interface {
 	GetAll(ctx context.Context) ([]models.Song, error)
 	GetFiltered(ctx context.Context, filter SongFilter, offset int, limit int) ([]models.Song, error)
 	GetById(ctx context.Context, id int) (*models.Song, error)
+	GetByIds(ctx context.Context, ids []int) ([]models.Song, error)
 	Save(ctx context.Context, song *models.Song) error
+	Update(ctx context.Context, id int, change func(song *models.Song) (*models.Song, error)) (*models.Song, error)
+	Upsert(ctx context.Context, song *models.Song) (bool, error)
 	Delete(ctx context.Context, id int) error
+	GetTrashed(ctx context.Context, offset int, limit int) ([]models.Song, error)
+	GetTrashedById(ctx context.Context, id int) (*models.Song, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetRevisions(ctx context.Context, id int) ([]models.SongRevision, error)
+	GetRevision(ctx context.Context, id, revision int) (*models.SongRevision, error)
 }
 
+// Actions recorded in song history
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// SongRepository works with songs of the library from the request context, see utils.WithLibrary
 type SongRepository struct {
 	db *sqlx.DB
 }
@@ -28,17 +49,81 @@ func NewSongRepository(db *sqlx.DB) ISongRepo {
 	return &SongRepository{db}
 }
 
-// Save saves a song to db if id not set, otherwise updates the existing song
+// Save saves a song to db if id not set, otherwise updates the existing song.
+// Every change is recorded in song history
 func (r *SongRepository) Save(ctx context.Context, song *models.Song) error {
+	defer metrics.ObserveQuery("song", "Save", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveSong(ctx, tx, song); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update locks the song, passes it to change and saves the song returned by change in the same transaction,
+// so concurrent updates of the song don't overwrite each other. Nothing is saved if change fails
+func (r *SongRepository) Update(
+	ctx context.Context, id int, change func(song *models.Song) (*models.Song, error),
+) (*models.Song, error) {
+	defer metrics.ObserveQuery("song", "Update", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	song := models.Song{}
+	query := `SELECT * FROM song WHERE id=$1 AND library=$2 AND deleted_at IS NULL FOR UPDATE`
+	span := startQuery(ctx, "SongRepository.Update", query)
+	err = tx.GetContext(ctx, &song, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, 1, err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: song with id %d doesn't exist", id)
+		}
+		return nil, err
+	}
+	songs := []models.Song{song}
+	if err := loadDetails(ctx, tx, songs); err != nil {
+		return nil, err
+	}
+	newSong, err := change(&songs[0])
+	if err != nil {
+		return nil, err
+	}
+	newSong.ID = &id
+	if err := saveSong(ctx, tx, newSong); err != nil {
+		return nil, err
+	}
+	return newSong, tx.Commit()
+}
+
+// saveSong creates the song if id is not set, otherwise updates it, and records the change in song history
+func saveSong(ctx context.Context, tx *sqlx.Tx, song *models.Song) error {
+	artistID, artist, err := resolveArtist(ctx, tx, song.Artist)
+	if err != nil {
+		return err
+	}
+	song.ArtistID = &artistID
+	song.Artist = artist
+	song.Library = utils.LibraryFromContext(ctx)
+
+	action := ActionUpdate
 	if song.ID != nil {
 		// Update song
 		query := `
             UPDATE song
-            SET name=$1, artist=$2, lyrics=$3, release_date=$4, url=$5
-            WHERE id=$6
+            SET name=$1, artist=$2, artist_id=$3, lyrics=$4, release_date=$5, url=$6
+            WHERE id=$7 AND library=$8 AND deleted_at IS NULL
             `
-		log.Debug().Msgf("Running query: %s", query)
-		_, err := r.db.ExecContext(ctx, query, song.Name, song.Artist, song.Lyrics, song.ReleaseDate, song.URL, *song.ID)
+		span := startQuery(ctx, "SongRepository.Save", query)
+		res, err := tx.ExecContext(ctx, query,
+			song.Name, song.Artist, song.ArtistID, song.Lyrics, song.ReleaseDate, song.URL, *song.ID, song.Library)
+		endQuery(span, affected(res), err)
 		if err != nil {
 			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
 				// Unique violation
@@ -46,19 +131,28 @@ func (r *SongRepository) Save(ctx context.Context, song *models.Song) error {
 			}
 			return err
 		}
-		return nil
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count != 1 {
+			return fmt.Errorf("not found error: song with id %d not found", *song.ID)
+		}
 	} else {
 		// Create new song
+		action = ActionCreate
 		query := `
             INSERT INTO
-            song(name, artist, lyrics, release_date, url)
-            VALUES($1, $2, $3, $4, $5)
+            song(library, name, artist, artist_id, lyrics, release_date, url)
+            VALUES($1, $2, $3, $4, $5, $6, $7)
             RETURNING id
             `
-		log.Debug().Msgf("Running query: %s", query)
-		row := r.db.QueryRowContext(ctx, query, song.Name, song.Artist, song.Lyrics, song.ReleaseDate, song.URL)
+		span := startQuery(ctx, "SongRepository.Save", query)
+		row := tx.QueryRowContext(ctx, query,
+			song.Library, song.Name, song.Artist, song.ArtistID, song.Lyrics, song.ReleaseDate, song.URL)
 
 		err := row.Err()
+		endQuery(span, 1, err)
 		if err != nil {
 			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
 				// Unique violation
@@ -70,59 +164,196 @@ func (r *SongRepository) Save(ctx context.Context, song *models.Song) error {
 		if err != nil {
 			return err
 		}
+	}
+	if err := saveCredits(ctx, tx, song); err != nil {
+		return err
+	}
+	if err := saveGenres(ctx, tx, song); err != nil {
+		return err
+	}
+	if err := reloadDetails(ctx, tx, song); err != nil {
+		return err
+	}
+	return recordRevision(ctx, tx, *song.ID, action)
+}
+
+// Upsert creates a song with the given id or replaces the existing one, returns true if the song was created.
+// Replaced song is restored from trash. Ids are shared by all libraries, so the id of a song
+// in another library is a duplicate
+func (r *SongRepository) Upsert(ctx context.Context, song *models.Song) (bool, error) {
+	defer metrics.ObserveQuery("song", "Upsert", time.Now())
+	if song.ID == nil {
+		return false, fmt.Errorf("ID must be set for upsert")
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	artistID, artist, err := resolveArtist(ctx, tx, song.Artist)
+	if err != nil {
+		return false, err
+	}
+	song.ArtistID = &artistID
+	song.Artist = artist
+	song.Library = utils.LibraryFromContext(ctx)
 
-		return nil
+	query := `
+        INSERT INTO
+        song(id, library, name, artist, artist_id, lyrics, release_date, url)
+        VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (id) DO UPDATE
+        SET name=EXCLUDED.name, artist=EXCLUDED.artist, artist_id=EXCLUDED.artist_id, lyrics=EXCLUDED.lyrics,
+            release_date=EXCLUDED.release_date, url=EXCLUDED.url, deleted_at=NULL
+        WHERE song.library=EXCLUDED.library
+        RETURNING (xmax = 0) AS created
+        `
+	span := startQuery(ctx, "SongRepository.Upsert", query)
+	var created bool
+	err = tx.QueryRowxContext(ctx, query,
+		*song.ID, song.Library, song.Name, song.Artist, song.ArtistID, song.Lyrics, song.ReleaseDate, song.URL).
+		Scan(&created)
+	endQuery(span, 1, err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Conflicting song is in another library
+			return false, fmt.Errorf("duplicate error: song with id %d already exists", *song.ID)
+		}
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			// Unique violation
+			if err.Constraint == "song_pkey" {
+				return false, fmt.Errorf("duplicate error: song with id %d already exists", *song.ID)
+			}
+			return false, fmt.Errorf("duplicate error: song with name %s and artist %s already exists", song.Name, song.Artist)
+		}
+		return false, err
+	}
+	action := ActionUpdate
+	if created {
+		action = ActionCreate
+		// Move sequence past the inserted id, so SERIAL doesn't generate it again
+		query = `
+            SELECT setval(
+                pg_get_serial_sequence('song', 'id'),
+                GREATEST($1, pg_sequence_last_value(pg_get_serial_sequence('song', 'id')::regclass))
+            )
+            `
+		log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+		if _, err := tx.ExecContext(ctx, query, *song.ID); err != nil {
+			return false, err
+		}
 	}
+	if err := saveCredits(ctx, tx, song); err != nil {
+		return false, err
+	}
+	if err := saveGenres(ctx, tx, song); err != nil {
+		return false, err
+	}
+	if err := reloadDetails(ctx, tx, song); err != nil {
+		return false, err
+	}
+	if err := recordRevision(ctx, tx, *song.ID, action); err != nil {
+		return false, err
+	}
+
+	return created, tx.Commit()
 }
 
 func (r *SongRepository) GetAll(ctx context.Context) ([]models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetAll", time.Now())
 	songs := []models.Song{}
-	query := `SELECT * FROM song ORDER BY id ASC`
-	log.Debug().Msgf("Running query: %s", query)
-	err := r.db.SelectContext(ctx, &songs, query)
+	query := `SELECT * FROM song WHERE library=$1 AND deleted_at IS NULL ORDER BY id ASC`
+	span := startQuery(ctx, "SongRepository.GetAll", query)
+	err := r.db.SelectContext(ctx, &songs, query, utils.LibraryFromContext(ctx))
+	endQuery(span, int64(len(songs)), err)
 	if err != nil {
 		return nil, err
 	}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
 
 	return songs, nil
 }
 
 func (r *SongRepository) GetById(ctx context.Context, id int) (*models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetById", time.Now())
 	song := models.Song{}
-	query := `SELECT * FROM song WHERE id=$1`
-	log.Debug().Msgf("Running query: %s", query)
-	err := r.db.GetContext(ctx, &song, query, id)
+	query := `SELECT * FROM song WHERE id=$1 AND library=$2 AND deleted_at IS NULL`
+	span := startQuery(ctx, "SongRepository.GetById", query)
+	err := r.db.GetContext(ctx, &song, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, 1, err)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("not found error: song with id %d doesn't exist", id)
 		}
 		return nil, err
 	}
-	return &song, nil
+	songs := []models.Song{song}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
+	return &songs[0], nil
+}
+
+// GetByIds returns songs with the given ids, missing and deleted songs and songs of other libraries are skipped
+func (r *SongRepository) GetByIds(ctx context.Context, ids []int) ([]models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetByIds", time.Now())
+	songs := []models.Song{}
+	query := `SELECT * FROM song WHERE id = ANY($1) AND library=$2 AND deleted_at IS NULL`
+	span := startQuery(ctx, "SongRepository.GetByIds", query)
+	err := r.db.SelectContext(ctx, &songs, query, pq.Array(ids), utils.LibraryFromContext(ctx))
+	endQuery(span, int64(len(songs)), err)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
+	return songs, nil
 }
 
 func (r *SongRepository) GetFiltered(ctx context.Context, filter SongFilter, offset, limit int) ([]models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetFiltered", time.Now())
 	songs := []models.Song{}
+	filter.Library = utils.LibraryFromContext(ctx)
 	// Construct query from filter
-	query := `SELECT * FROM song WHERE 1=1`
-	log.Debug().Msgf("Running query: %s", query)
-	count := 1
+	query := `SELECT * FROM song WHERE library= :library AND deleted_at IS NULL`
 	if filter.Name != "" {
 		query += ` AND name= :name`
-		count++
 	}
 	if filter.Artist != "" {
-		query += ` AND artist= :artist`
-		count++
+		// Artist can be referred by name or alias, songs where the artist is credited match as well
+		query += ` AND (
+            artist_id IN (SELECT id FROM artist WHERE name= :artist OR :artist = ANY(aliases))
+            OR id IN (
+                SELECT sc.song_id FROM song_credit sc JOIN artist ON artist.id = sc.artist_id
+                WHERE artist.name= :artist OR :artist = ANY(artist.aliases)
+            ))`
+	}
+	if filter.ArtistID != 0 {
+		query += ` AND (artist_id= :artist_id OR id IN (SELECT song_id FROM song_credit WHERE artist_id= :artist_id))`
+	}
+	if filter.Genre != "" {
+		query += ` AND id IN (
+            SELECT sg.song_id FROM song_genre sg JOIN genre ON genre.id = sg.genre_id WHERE genre.name= :genre
+            )`
+	}
+	if len(filter.Tags) > 0 {
+		tagged := `SELECT st.song_id FROM song_tag st JOIN tag ON tag.id = st.tag_id WHERE tag.name = ANY(:tags)`
+		if filter.TagMatch == TagMatchAll {
+			// Every tag should be found, tags in filter are unique
+			tagged += ` GROUP BY st.song_id HAVING COUNT(*) = cardinality(CAST(:tags AS text[]))`
+		}
+		query += ` AND id IN (` + tagged + `)`
 	}
 	t := utils.CustomDate{}
 	if filter.After != t {
 		query += ` AND release_date >= :after`
-		count++
 	}
 	if filter.Before != t {
 		query += ` AND release_date <= :before`
-		count++
 	}
 
 	boundQuery, filterArgs, err := r.db.BindNamed(query, filter)
@@ -130,26 +361,43 @@ func (r *SongRepository) GetFiltered(ctx context.Context, filter SongFilter, off
 		return nil, err
 	}
 
-	boundQuery += ` ORDER BY id ASC`
-	boundQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d", count, count+1)
+	order, ok := songSorts[filter.Sort]
+	if !ok {
+		order = songSorts["id"]
+	}
+	boundQuery += ` ORDER BY ` + order
+	boundQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(filterArgs)+1, len(filterArgs)+2)
 
-	log.Debug().Msgf("Running query: %s", boundQuery)
-	log.Debug().Msgf("Filter args: %v", filterArgs)
-	log.Debug().Msgf("Limit: %d, Offset: %d", limit, offset)
+	span := startQuery(ctx, "SongRepository.GetFiltered", boundQuery)
+	log.Ctx(ctx).Debug().Msgf("Filter args: %v", filterArgs)
+	log.Ctx(ctx).Debug().Msgf("Limit: %d, Offset: %d", limit, offset)
 	// Append limit and offset to the end of the query
 	args := append(filterArgs, limit, offset)
 	err = r.db.SelectContext(ctx, &songs, boundQuery, args...)
+	endQuery(span, int64(len(songs)), err)
 	if err != nil {
 		return nil, err
 	}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
 
 	return songs, nil
 }
 
+// Delete moves the song to trash
 func (r *SongRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM song WHERE id = $1`
-	log.Debug().Msgf("Running query: %s", query)
-	res, err := r.db.ExecContext(ctx, query, id)
+	defer metrics.ObserveQuery("song", "Delete", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE song SET deleted_at=NOW() WHERE id=$1 AND library=$2 AND deleted_at IS NULL`
+	span := startQuery(ctx, "SongRepository.Delete", query)
+	res, err := tx.ExecContext(ctx, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, affected(res), err)
 	if err != nil {
 		return err
 	}
@@ -160,5 +408,160 @@ func (r *SongRepository) Delete(ctx context.Context, id int) error {
 	if count != 1 {
 		return fmt.Errorf("not found error: song with id %d not found", id)
 	}
-	return nil
+	if err := recordRevision(ctx, tx, id, ActionDelete); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTrashed returns songs in trash, most recently deleted first
+func (r *SongRepository) GetTrashed(ctx context.Context, offset, limit int) ([]models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetTrashed", time.Now())
+	songs := []models.Song{}
+	query := `
+        SELECT * FROM song
+        WHERE library=$1 AND deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC, id ASC
+        LIMIT $2 OFFSET $3
+        `
+	span := startQuery(ctx, "SongRepository.GetTrashed", query)
+	err := r.db.SelectContext(ctx, &songs, query, utils.LibraryFromContext(ctx), limit, offset)
+	endQuery(span, int64(len(songs)), err)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+// GetTrashedById returns the song with the given id if it is in trash
+func (r *SongRepository) GetTrashedById(ctx context.Context, id int) (*models.Song, error) {
+	defer metrics.ObserveQuery("song", "GetTrashedById", time.Now())
+	song := models.Song{}
+	query := `SELECT * FROM song WHERE id=$1 AND library=$2 AND deleted_at IS NOT NULL`
+	span := startQuery(ctx, "SongRepository.GetTrashedById", query)
+	err := r.db.GetContext(ctx, &song, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, 1, err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: song with id %d not found in trash", id)
+		}
+		return nil, err
+	}
+	songs := []models.Song{song}
+	if err := loadDetails(ctx, r.db, songs); err != nil {
+		return nil, err
+	}
+	return &songs[0], nil
+}
+
+// Restore moves the song out of trash
+func (r *SongRepository) Restore(ctx context.Context, id int) error {
+	defer metrics.ObserveQuery("song", "Restore", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE song SET deleted_at=NULL WHERE id=$1 AND library=$2 AND deleted_at IS NOT NULL`
+	span := startQuery(ctx, "SongRepository.Restore", query)
+	res, err := tx.ExecContext(ctx, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, affected(res), err)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			// Unique violation
+			return fmt.Errorf("duplicate error: song with the same name and artist as song %d already exists", id)
+		}
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("not found error: song with id %d not found in trash", id)
+	}
+	if err := recordRevision(ctx, tx, id, ActionRestore); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge permanently deletes songs moved to trash before deletedBefore, returns the number of deleted songs.
+// History of purged songs is kept. Purge runs as a background job for songs of all libraries
+func (r *SongRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	defer metrics.ObserveQuery("song", "Purge", time.Now())
+	query := `DELETE FROM song WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	span := startQuery(ctx, "SongRepository.Purge", query)
+	res, err := r.db.ExecContext(ctx, query, deletedBefore)
+	endQuery(span, affected(res), err)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetRevisions returns history of the song, oldest revision first
+func (r *SongRepository) GetRevisions(ctx context.Context, id int) ([]models.SongRevision, error) {
+	defer metrics.ObserveQuery("song", "GetRevisions", time.Now())
+	revisions := []models.SongRevision{}
+	query := `SELECT * FROM song_revision WHERE song_id=$1 AND library=$2 ORDER BY revision ASC`
+	span := startQuery(ctx, "SongRepository.GetRevisions", query)
+	err := r.db.SelectContext(ctx, &revisions, query, id, utils.LibraryFromContext(ctx))
+	endQuery(span, int64(len(revisions)), err)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *SongRepository) GetRevision(ctx context.Context, id, revision int) (*models.SongRevision, error) {
+	defer metrics.ObserveQuery("song", "GetRevision", time.Now())
+	rev := models.SongRevision{}
+	query := `SELECT * FROM song_revision WHERE song_id=$1 AND library=$2 AND revision=$3`
+	span := startQuery(ctx, "SongRepository.GetRevision", query)
+	err := r.db.GetContext(ctx, &rev, query, id, utils.LibraryFromContext(ctx), revision)
+	endQuery(span, 1, err)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("not found error: revision %d of song with id %d doesn't exist", revision, id)
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// recordRevision stores a snapshot of the song in its history, in the library of the song.
+// Actor and source of the change are taken from ctx. The song row stays locked until tx ends,
+// so concurrent changes of the song get consecutive revisions
+func recordRevision(ctx context.Context, tx *sqlx.Tx, id int, action string) error {
+	songs := []models.Song{}
+	if err := tx.SelectContext(ctx, &songs, `SELECT * FROM song WHERE id=$1 FOR UPDATE`, id); err != nil {
+		return err
+	}
+	// Tags are not part of song history
+	if err := loadCredits(ctx, tx, songs); err != nil {
+		return err
+	}
+	if err := loadGenres(ctx, tx, songs); err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(songs[0])
+	if err != nil {
+		return err
+	}
+	query := `
+        INSERT INTO
+        song_revision(song_id, library, revision, action, snapshot, actor, source)
+        VALUES($1, $2, (SELECT COALESCE(MAX(revision), 0) + 1 FROM song_revision WHERE song_id=$1), $3, $4, $5, $6)
+        `
+	span := startQuery(ctx, "recordRevision", query)
+	res, err := tx.ExecContext(ctx, query,
+		id, songs[0].Library, action, snapshot, utils.ActorFromContext(ctx), utils.ChangeSourceFromContext(ctx))
+	endQuery(span, affected(res), err)
+	return err
 }
@@ -8,6 +8,8 @@ import (
 	"music-lib/internal/utils"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,6 +18,14 @@ import (
 )
 
 var songRepo ISongRepo
+var artistRepo IArtistRepo
+var tagRepo ITagRepo
+var playlistRepo IPlaylistRepo
+var apiKeyRepo IAPIKeyRepo
+var favoriteRepo IFavoriteRepo
+var ratingRepo IRatingRepo
+var healthRepo IHealthRepo
+var albumRepo IAlbumRepo
 
 func findProjectRoot() (string, error) {
 	dir, err := os.Getwd()
@@ -63,6 +73,14 @@ func TestMain(m *testing.M) {
 		panic(fmt.Sprintf("Failed to connect to database: %v", err))
 	}
 	songRepo = NewSongRepository(db)
+	artistRepo = NewArtistRepository(db)
+	tagRepo = NewTagRepository(db)
+	playlistRepo = NewPlaylistRepository(db)
+	apiKeyRepo = NewAPIKeyRepository(db)
+	favoriteRepo = NewFavoriteRepository(db)
+	ratingRepo = NewRatingRepository(db)
+	healthRepo = NewHealthRepository(db)
+	albumRepo = NewAlbumRepository(db)
 
 	m.Run()
 
@@ -225,3 +243,693 @@ func TestGetAll(t *testing.T) {
 		PrintSong(&song)
 	}
 }
+
+func TestUpsertCreate(t *testing.T) {
+	// Create song with requested id
+	id := 500
+	song := models.Song{
+		ID:          &id,
+		Name:        "Upserted Song",
+		Artist:      "Song Artist",
+		Lyrics:      "Song Lyrics",
+		ReleaseDate: utils.CustomDate(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+	}
+	created, err := songRepo.Upsert(context.Background(), &song)
+	if err != nil {
+		t.Fatalf("Error upserting song: %v", err)
+	}
+	if !created {
+		t.Fatalf("Expected song to be created")
+	}
+	// Sequence must continue after upserted id
+	newSong := models.Song{
+		Name:        "Song After Upsert",
+		Artist:      "Song Artist",
+		Lyrics:      "Song Lyrics",
+		ReleaseDate: utils.CustomDate(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+	}
+	if err := songRepo.Save(context.Background(), &newSong); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	if *newSong.ID <= id {
+		t.Fatalf("Expected id greater than %d, got %d", id, *newSong.ID)
+	}
+}
+
+func TestUpsertReplace(t *testing.T) {
+	id := 500
+	song := models.Song{
+		ID:          &id,
+		Name:        "Upserted Song",
+		Artist:      "Song Artist",
+		Lyrics:      "Song Replaced Lyrics",
+		ReleaseDate: utils.CustomDate(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+	}
+	created, err := songRepo.Upsert(context.Background(), &song)
+	if err != nil {
+		t.Fatalf("Error upserting song: %v", err)
+	}
+	if created {
+		t.Fatalf("Expected song to be replaced")
+	}
+}
+
+func TestGetTrashed(t *testing.T) {
+	songs, err := songRepo.GetTrashed(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting deleted songs: %v", err)
+	}
+	if len(songs) != 1 || *songs[0].ID != 1 {
+		t.Fatalf("Expected deleted song with id 1, got %v", songs)
+	}
+}
+
+func TestGetTrashedById(t *testing.T) {
+	song, err := songRepo.GetTrashedById(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error getting deleted song: %v", err)
+	}
+	if song.DeletedAt == nil {
+		t.Fatalf("Expected song in trash, got %v", song)
+	}
+	if _, err := songRepo.GetTrashedById(context.Background(), 2); err == nil {
+		t.Fatalf("Expected error for song not in trash, got nil")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	err := songRepo.Restore(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error restoring song: %v", err)
+	}
+	song, err := songRepo.GetById(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error getting restored song: %v", err)
+	}
+
+	PrintSong(song)
+}
+
+func TestRestoreNotInTrash(t *testing.T) {
+	err := songRepo.Restore(context.Background(), 1)
+	if err == nil {
+		t.Fatalf("Expected error, got nil and restored")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	if err := songRepo.Delete(context.Background(), 1); err != nil {
+		t.Fatalf("Error deleting song: %v", err)
+	}
+	count, err := songRepo.Purge(context.Background(), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Error purging songs: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected 1 purged song, got %d", count)
+	}
+}
+
+func TestGetRevisions(t *testing.T) {
+	ctx := utils.WithActor(context.Background(), "tester")
+	song, err := songRepo.GetById(ctx, 2)
+	if err != nil {
+		t.Fatalf("Error getting song: %v", err)
+	}
+	song.Lyrics = "Edited lyrics"
+	if err := songRepo.Save(ctx, song); err != nil {
+		t.Fatalf("Error updating song: %v", err)
+	}
+	revisions, err := songRepo.GetRevisions(ctx, 2)
+	if err != nil {
+		t.Fatalf("Error getting revisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+	}
+	last := revisions[1]
+	if last.Action != ActionUpdate || last.Actor != "tester" || last.Source != utils.SourceAPI {
+		t.Fatalf("Unexpected revision: %+v", last)
+	}
+}
+
+func TestConcurrentRevisions(t *testing.T) {
+	ctx := context.Background()
+	song := models.Song{Name: "Concurrent", Artist: "Song Artist", Lyrics: "0", URL: "https://concurrent.song.com"}
+	if err := songRepo.Save(ctx, &song); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	const writers = 8
+	errs := make(chan error, writers)
+	wg := sync.WaitGroup{}
+	for i := 1; i <= writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			update := song
+			update.Lyrics = fmt.Sprint(i)
+			errs <- songRepo.Save(ctx, &update)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Error saving song concurrently: %v", err)
+		}
+	}
+	revisions, err := songRepo.GetRevisions(ctx, *song.ID)
+	if err != nil {
+		t.Fatalf("Error getting revisions: %v", err)
+	}
+	if len(revisions) != writers+1 {
+		t.Fatalf("Expected %d revisions, got %d", writers+1, len(revisions))
+	}
+	for i, rev := range revisions {
+		if rev.Revision != i+1 {
+			t.Fatalf("Expected revision %d, got %d", i+1, rev.Revision)
+		}
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	song := models.Song{Name: "Concurrent Update", Artist: "Song Artist", Lyrics: "", URL: "https://update.song.com"}
+	if err := songRepo.Save(ctx, &song); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	// Every update appends to the lyrics it reads, none of them is lost
+	const writers = 8
+	errs := make(chan error, writers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := songRepo.Update(ctx, *song.ID, func(stored *models.Song) (*models.Song, error) {
+				stored.Lyrics += "x"
+				return stored, nil
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Error updating song concurrently: %v", err)
+		}
+	}
+	updated, err := songRepo.GetById(ctx, *song.ID)
+	if err != nil {
+		t.Fatalf("Error getting song: %v", err)
+	}
+	if updated.Lyrics != strings.Repeat("x", writers) {
+		t.Fatalf("Expected %d updates, got lyrics %q", writers, updated.Lyrics)
+	}
+	// Failed change isn't saved
+	_, err = songRepo.Update(ctx, *song.ID, func(stored *models.Song) (*models.Song, error) {
+		return nil, fmt.Errorf("conflict error: test failed")
+	})
+	if err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Fatalf("Expected change error, got %v", err)
+	}
+	if _, err := songRepo.Update(ctx, -1, func(stored *models.Song) (*models.Song, error) { return stored, nil }); err == nil {
+		t.Fatalf("Expected not found error updating missing song")
+	}
+}
+
+func TestArtistAlias(t *testing.T) {
+	artist := models.Artist{
+		Name:    "The Beatles",
+		Aliases: []string{"Beatles"},
+	}
+	if err := artistRepo.Save(context.Background(), &artist); err != nil {
+		t.Fatalf("Error saving artist: %v", err)
+	}
+	// Song saved with alias refers to the canonical artist
+	song := models.Song{
+		Name:        "Yesterday",
+		Artist:      "Beatles",
+		Lyrics:      "Yesterday, all my troubles seemed so far away",
+		ReleaseDate: utils.CustomDate(time.Date(1965, 8, 6, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+	}
+	if err := songRepo.Save(context.Background(), &song); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	if *song.ArtistID != *artist.ID || song.Artist != "The Beatles" {
+		t.Fatalf("Expected artist %d The Beatles, got %d %s", *artist.ID, *song.ArtistID, song.Artist)
+	}
+	songs, err := songRepo.GetFiltered(context.Background(), SongFilter{Artist: "Beatles"}, 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting filtered songs: %v", err)
+	}
+	if len(songs) != 1 {
+		t.Fatalf("Expected 1 song, got %d", len(songs))
+	}
+}
+
+func TestArtistRename(t *testing.T) {
+	artists, err := artistRepo.GetAll(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatalf("Error getting artists: %v", err)
+	}
+	for _, artist := range artists {
+		if artist.Name != "The Beatles" {
+			continue
+		}
+		artist.Name = "Beatles, The"
+		if err := artistRepo.Save(context.Background(), &artist); err != nil {
+			t.Fatalf("Error renaming artist: %v", err)
+		}
+		songs, err := songRepo.GetFiltered(context.Background(), SongFilter{ArtistID: *artist.ID}, 0, 10)
+		if err != nil {
+			t.Fatalf("Error getting songs of artist: %v", err)
+		}
+		for _, song := range songs {
+			if song.Artist != "Beatles, The" {
+				t.Fatalf("Expected song artist to be renamed, got %s", song.Artist)
+			}
+		}
+		return
+	}
+	t.Fatalf("Artist not found")
+}
+
+func TestSongCredits(t *testing.T) {
+	song := models.Song{
+		Name:        "Under Pressure",
+		Artist:      "Queen",
+		Lyrics:      "Pressure pushing down on me",
+		ReleaseDate: utils.CustomDate(time.Date(1981, 10, 26, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+		Credits: []models.Credit{
+			{Artist: "David Bowie", Role: models.RoleFeatured},
+			{Artist: "Queen", Role: models.RolePrimary},
+		},
+	}
+	if err := songRepo.Save(context.Background(), &song); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	// Main artist is the only primary credit
+	if len(song.Credits) != 2 || song.Credits[0].Artist != "Queen" || song.Credits[1].Role != models.RoleFeatured {
+		t.Fatalf("Unexpected credits: %+v", song.Credits)
+	}
+	// Song is found by the featured artist
+	songs, err := songRepo.GetFiltered(context.Background(), SongFilter{Artist: "David Bowie"}, 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting filtered songs: %v", err)
+	}
+	if len(songs) != 1 || *songs[0].ID != *song.ID {
+		t.Fatalf("Expected song %d, got %d songs", *song.ID, len(songs))
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	songs, err := songRepo.GetFiltered(context.Background(), SongFilter{}, 0, 2)
+	if err != nil {
+		t.Fatalf("Error getting songs: %v", err)
+	}
+	if len(songs) != 2 {
+		t.Fatalf("Expected 2 songs, got %d", len(songs))
+	}
+	if err := tagRepo.AddToSong(context.Background(), *songs[0].ID, []string{"chill", "night"}); err != nil {
+		t.Fatalf("Error adding tags: %v", err)
+	}
+	if err := tagRepo.AddToSong(context.Background(), *songs[1].ID, []string{"chill"}); err != nil {
+		t.Fatalf("Error adding tags: %v", err)
+	}
+	tags := []string{"chill", "night"}
+	anyTagged, err := songRepo.GetFiltered(context.Background(), SongFilter{Tags: tags, TagMatch: TagMatchAny}, 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting songs by tags: %v", err)
+	}
+	if len(anyTagged) != 2 {
+		t.Fatalf("Expected 2 songs with any tag, got %d", len(anyTagged))
+	}
+	allTagged, err := songRepo.GetFiltered(context.Background(), SongFilter{Tags: tags, TagMatch: TagMatchAll}, 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting songs by tags: %v", err)
+	}
+	if len(allTagged) != 1 || *allTagged[0].ID != *songs[0].ID {
+		t.Fatalf("Expected song %d with all tags, got %d songs", *songs[0].ID, len(allTagged))
+	}
+	if err := tagRepo.RemoveFromSong(context.Background(), *songs[0].ID, "night"); err != nil {
+		t.Fatalf("Error removing tag: %v", err)
+	}
+	counts, err := tagRepo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting tags: %v", err)
+	}
+	if len(counts) != 1 || counts[0].Name != "chill" || counts[0].Count != 2 {
+		t.Fatalf("Unexpected tag counts: %+v", counts)
+	}
+}
+
+func TestPlaylistPositions(t *testing.T) {
+	playlist := models.Playlist{Name: "Mix", Owner: "tester", Visibility: models.VisibilityPrivate}
+	if err := playlistRepo.Save(context.Background(), &playlist); err != nil {
+		t.Fatalf("Error saving playlist: %v", err)
+	}
+	songs, err := songRepo.GetFiltered(context.Background(), SongFilter{}, 0, 3)
+	if err != nil {
+		t.Fatalf("Error getting songs: %v", err)
+	}
+	// Every song is inserted at the top, so entries end up in reverse order
+	for _, song := range songs {
+		entry := models.PlaylistEntry{PlaylistID: *playlist.ID, SongID: *song.ID, Position: 1}
+		if err := playlistRepo.InsertEntry(context.Background(), &entry); err != nil {
+			t.Fatalf("Error inserting entry: %v", err)
+		}
+	}
+	entries, err := playlistRepo.GetEntries(context.Background(), *playlist.ID)
+	if err != nil {
+		t.Fatalf("Error getting entries: %v", err)
+	}
+	if len(entries) != len(songs) || entries[0].SongID != *songs[len(songs)-1].ID {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	// Move the first entry past the end and remove the new first one
+	first := entries[0]
+	first.Position = 100
+	if err := playlistRepo.MoveEntry(context.Background(), &first); err != nil {
+		t.Fatalf("Error moving entry: %v", err)
+	}
+	if first.Position != len(songs) {
+		t.Fatalf("Expected entry at position %d, got %d", len(songs), first.Position)
+	}
+	if err := playlistRepo.RemoveEntry(context.Background(), *playlist.ID, entries[1].ID); err != nil {
+		t.Fatalf("Error removing entry: %v", err)
+	}
+	entries, err = playlistRepo.GetEntries(context.Background(), *playlist.ID)
+	if err != nil {
+		t.Fatalf("Error getting entries: %v", err)
+	}
+	for i, entry := range entries {
+		if entry.Position != i+1 {
+			t.Fatalf("Expected position %d, got %d", i+1, entry.Position)
+		}
+	}
+}
+
+func TestAlbumTracks(t *testing.T) {
+	songs := []models.Song{}
+	for _, name := range []string{"Side A", "Side B", "Bonus"} {
+		song := models.Song{Name: name, Artist: "Album Artist", Lyrics: name, URL: "https://example.com/album"}
+		if err := songRepo.Save(context.Background(), &song); err != nil {
+			t.Fatalf("Error saving song: %v", err)
+		}
+		songs = append(songs, song)
+	}
+	album := models.Album{Title: "Double Album", Artist: "Album Artist", ReleaseDate: songs[0].ReleaseDate}
+	if err := albumRepo.Save(context.Background(), &album); err != nil {
+		t.Fatalf("Error saving album: %v", err)
+	}
+	// Tracks are added out of order and come back ordered by disc and track number
+	tracks := []models.AlbumTrack{
+		{AlbumID: *album.ID, SongID: *songs[2].ID, DiscNumber: 2, TrackNumber: 1},
+		{AlbumID: *album.ID, SongID: *songs[1].ID, DiscNumber: 1, TrackNumber: 2},
+		{AlbumID: *album.ID, SongID: *songs[0].ID, DiscNumber: 1, TrackNumber: 1},
+	}
+	for i := range tracks {
+		if err := albumRepo.AddTrack(context.Background(), &tracks[i]); err != nil {
+			t.Fatalf("Error adding track: %v", err)
+		}
+	}
+	got, err := albumRepo.GetTracks(context.Background(), *album.ID)
+	if err != nil {
+		t.Fatalf("Error getting tracks: %v", err)
+	}
+	if len(got) != len(songs) {
+		t.Fatalf("Expected %d tracks, got %d", len(songs), len(got))
+	}
+	for i, track := range got {
+		if track.SongID != *songs[i].ID {
+			t.Fatalf("Expected song %d at position %d, got %+v", *songs[i].ID, i, got)
+		}
+	}
+
+	// Songs of another library can't be put on the album
+	teamA := utils.WithLibrary(context.Background(), "team-a")
+	other := models.Song{Name: "Stranger", Artist: "Album Artist", Lyrics: "other", URL: "https://example.com/other"}
+	if err := songRepo.Save(teamA, &other); err != nil {
+		t.Fatalf("Error saving song in another library: %v", err)
+	}
+	track := models.AlbumTrack{AlbumID: *album.ID, SongID: *other.ID, DiscNumber: 2, TrackNumber: 2}
+	if err := albumRepo.AddTrack(context.Background(), &track); err == nil || !strings.Contains(err.Error(), "song") {
+		t.Fatalf("Expected song not found error adding song of another library, got %v", err)
+	}
+
+	// Deleting the album removes its tracks but keeps the songs
+	if err := albumRepo.Delete(context.Background(), *album.ID); err != nil {
+		t.Fatalf("Error deleting album with tracks: %v", err)
+	}
+	got, err = albumRepo.GetTracks(context.Background(), *album.ID)
+	if err != nil {
+		t.Fatalf("Error getting tracks: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Expected tracks of deleted album to be removed, got %+v", got)
+	}
+	if _, err := songRepo.GetById(context.Background(), *songs[0].ID); err != nil {
+		t.Fatalf("Expected song of deleted album to be kept, got %v", err)
+	}
+}
+
+func TestAPIKeyRevoke(t *testing.T) {
+	key := models.APIKey{Name: "batch", Prefix: "mlk_test", KeyHash: strings.Repeat("a", 64), Scopes: []string{"read"}}
+	if err := apiKeyRepo.Save(context.Background(), &key); err != nil {
+		t.Fatalf("Error saving api key: %v", err)
+	}
+	if err := apiKeyRepo.Revoke(context.Background(), *key.ID, ""); err != nil {
+		t.Fatalf("Error revoking api key: %v", err)
+	}
+	revoked, err := apiKeyRepo.GetByHash(context.Background(), key.KeyHash)
+	if err != nil {
+		t.Fatalf("Error getting api key: %v", err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Fatalf("Expected api key to be revoked")
+	}
+}
+
+func TestAPIKeyLibraryScope(t *testing.T) {
+	library := "team-a"
+	key := models.APIKey{Name: "team-a-import", Prefix: "mlk_scop", KeyHash: strings.Repeat("b", 64), Scopes: []string{"admin"}, Library: &library}
+	if err := apiKeyRepo.Save(context.Background(), &key); err != nil {
+		t.Fatalf("Error saving api key: %v", err)
+	}
+	keys, err := apiKeyRepo.GetAll(context.Background(), "team-b")
+	if err != nil {
+		t.Fatalf("Error getting api keys: %v", err)
+	}
+	for _, k := range keys {
+		if *k.ID == *key.ID {
+			t.Fatalf("Expected key of team-a not to be listed for team-b")
+		}
+	}
+	if err := apiKeyRepo.Revoke(context.Background(), *key.ID, "team-b"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error revoking key of other library, got %v", err)
+	}
+	if err := apiKeyRepo.Revoke(context.Background(), *key.ID, "team-a"); err != nil {
+		t.Fatalf("Error revoking api key: %v", err)
+	}
+}
+
+func TestLibraryIsolation(t *testing.T) {
+	teamA := utils.WithLibrary(context.Background(), "team-a")
+	teamB := utils.WithLibrary(context.Background(), "team-b")
+	// The same song can be in both libraries
+	songA := models.Song{Name: "Shared", Artist: "Isolated", Lyrics: "a", URL: "https://example.com/a"}
+	if err := songRepo.Save(teamA, &songA); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	songB := models.Song{Name: "Shared", Artist: "Isolated", Lyrics: "b", URL: "https://example.com/b"}
+	if err := songRepo.Save(teamB, &songB); err != nil {
+		t.Fatalf("Error saving song in another library: %v", err)
+	}
+	if _, err := songRepo.GetById(teamB, *songA.ID); err == nil {
+		t.Fatalf("Expected song of another library to be not found")
+	}
+	if err := songRepo.Delete(teamB, *songA.ID); err == nil {
+		t.Fatalf("Expected song of another library not to be deleted")
+	}
+	// Upsert doesn't take over the id of a song in another library
+	songB.ID = songA.ID
+	if _, err := songRepo.Upsert(teamB, &songB); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("Expected duplicate error, got %v", err)
+	}
+	songs, err := songRepo.GetFiltered(teamA, SongFilter{Artist: "Isolated"}, 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting songs: %v", err)
+	}
+	if len(songs) != 1 || songs[0].Lyrics != "a" {
+		t.Fatalf("Expected only the song of the library, got %+v", songs)
+	}
+	// Tags of other libraries are not listed
+	if err := tagRepo.AddToSong(teamA, *songA.ID, []string{"team-a-only"}); err != nil {
+		t.Fatalf("Error adding tags: %v", err)
+	}
+	tags, err := tagRepo.GetAll(teamB)
+	if err != nil {
+		t.Fatalf("Error getting tags: %v", err)
+	}
+	for _, tag := range tags {
+		if tag.Name == "team-a-only" {
+			t.Fatalf("Expected tag of another library not to be listed")
+		}
+	}
+}
+
+func TestCatalogLibraryIsolation(t *testing.T) {
+	teamA := utils.WithLibrary(context.Background(), "team-a")
+	teamB := utils.WithLibrary(context.Background(), "team-b")
+	songA := models.Song{Name: "Catalog", Artist: "Tenant Artist", Lyrics: "a", URL: "https://example.com/a"}
+	if err := songRepo.Save(teamA, &songA); err != nil {
+		t.Fatalf("Error saving song: %v", err)
+	}
+	songB := models.Song{Name: "Catalog", Artist: "Tenant Artist", Lyrics: "b", URL: "https://example.com/b"}
+	if err := songRepo.Save(teamB, &songB); err != nil {
+		t.Fatalf("Error saving song in another library: %v", err)
+	}
+	if *songA.ArtistID == *songB.ArtistID {
+		t.Fatalf("Expected every library to have its own artist")
+	}
+
+	// Artists of another library can't be read, renamed or deleted
+	if _, err := artistRepo.GetById(teamB, *songA.ArtistID); err == nil {
+		t.Fatalf("Expected artist of another library to be not found")
+	}
+	renamed := models.Artist{ID: songA.ArtistID, Name: "Hijacked"}
+	if err := artistRepo.Save(teamB, &renamed); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error renaming artist of another library, got %v", err)
+	}
+	if err := artistRepo.Delete(teamB, *songA.ArtistID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error deleting artist of another library, got %v", err)
+	}
+	// Renaming the artist of one library leaves songs of another library alone
+	own := models.Artist{ID: songB.ArtistID, Name: "Tenant Artist B"}
+	if err := artistRepo.Save(teamB, &own); err != nil {
+		t.Fatalf("Error renaming artist: %v", err)
+	}
+	song, err := songRepo.GetById(teamA, *songA.ID)
+	if err != nil {
+		t.Fatalf("Error getting song: %v", err)
+	}
+	if song.Artist != "Tenant Artist" {
+		t.Fatalf("Expected song of another library to keep its artist, got %s", song.Artist)
+	}
+
+	// Albums of another library can't be read, changed, deleted or get tracks
+	album := models.Album{Title: "Tenant Album", Artist: "Tenant Artist", ReleaseDate: songA.ReleaseDate}
+	if err := albumRepo.Save(teamA, &album); err != nil {
+		t.Fatalf("Error saving album: %v", err)
+	}
+	if _, err := albumRepo.GetById(teamB, *album.ID); err == nil {
+		t.Fatalf("Expected album of another library to be not found")
+	}
+	changed := album
+	changed.Title = "Hijacked"
+	if err := albumRepo.Save(teamB, &changed); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error updating album of another library, got %v", err)
+	}
+	track := models.AlbumTrack{AlbumID: *album.ID, SongID: *songB.ID, DiscNumber: 1, TrackNumber: 1}
+	if err := albumRepo.AddTrack(teamB, &track); err == nil || !strings.Contains(err.Error(), "album") {
+		t.Fatalf("Expected album not found error adding track in another library, got %v", err)
+	}
+	if err := albumRepo.Delete(teamB, *album.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error deleting album of another library, got %v", err)
+	}
+
+	// Public playlists of another library are neither listed nor changed
+	playlist := models.Playlist{Name: "Tenant Mix", Owner: "tester", Visibility: models.VisibilityPublic}
+	if err := playlistRepo.Save(teamA, &playlist); err != nil {
+		t.Fatalf("Error saving playlist: %v", err)
+	}
+	visible, err := playlistRepo.GetVisible(teamB, "tester", 0, 100)
+	if err != nil {
+		t.Fatalf("Error getting playlists: %v", err)
+	}
+	for _, p := range visible {
+		if *p.ID == *playlist.ID {
+			t.Fatalf("Expected playlist of another library not to be listed")
+		}
+	}
+	if _, err := playlistRepo.GetById(teamB, *playlist.ID); err == nil {
+		t.Fatalf("Expected playlist of another library to be not found")
+	}
+	entry := models.PlaylistEntry{PlaylistID: *playlist.ID, SongID: *songB.ID}
+	if err := playlistRepo.InsertEntry(teamB, &entry); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error adding entry in another library, got %v", err)
+	}
+	if err := playlistRepo.Delete(teamB, *playlist.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected not found error deleting playlist of another library, got %v", err)
+	}
+}
+
+func TestRatingsAndFavorites(t *testing.T) {
+	songs, err := songRepo.GetFiltered(context.Background(), SongFilter{}, 0, 2)
+	if err != nil || len(songs) < 2 {
+		t.Fatalf("Error getting songs: %v", err)
+	}
+	// The second song is rated higher, rating again replaces the previous rating
+	ratings := []models.Rating{
+		{User: "alice", SongID: *songs[0].ID, Rating: 2},
+		{User: "alice", SongID: *songs[1].ID, Rating: 3},
+		{User: "bob", SongID: *songs[1].ID, Rating: 4},
+		{User: "alice", SongID: *songs[1].ID, Rating: 5},
+	}
+	for i := range ratings {
+		if err := ratingRepo.Save(context.Background(), &ratings[i]); err != nil {
+			t.Fatalf("Error saving rating: %v", err)
+		}
+	}
+	song, err := songRepo.GetById(context.Background(), *songs[1].ID)
+	if err != nil {
+		t.Fatalf("Error getting song: %v", err)
+	}
+	if song.RatingAvg != 4.5 || song.RatingCount != 2 {
+		t.Fatalf("Expected rating 4.5 of 2, got %v of %d", song.RatingAvg, song.RatingCount)
+	}
+	top, err := songRepo.GetFiltered(context.Background(), SongFilter{Sort: "-rating"}, 0, 1)
+	if err != nil {
+		t.Fatalf("Error getting songs: %v", err)
+	}
+	if len(top) != 1 || *top[0].ID != *songs[1].ID {
+		t.Fatalf("Expected song %d to be the top rated, got %+v", *songs[1].ID, top)
+	}
+
+	favorite := models.Favorite{User: "alice", SongID: *songs[0].ID}
+	if err := favoriteRepo.Save(context.Background(), &favorite); err != nil {
+		t.Fatalf("Error saving favorite: %v", err)
+	}
+	favorites, err := favoriteRepo.GetByUser(context.Background(), "alice", 0, 10)
+	if err != nil {
+		t.Fatalf("Error getting favorites: %v", err)
+	}
+	if len(favorites) != 1 || favorites[0].SongID != *songs[0].ID {
+		t.Fatalf("Unexpected favorites: %+v", favorites)
+	}
+	if err := favoriteRepo.Delete(context.Background(), "bob", *songs[0].ID); err == nil {
+		t.Fatalf("Expected favorite of another user not to be deleted")
+	}
+}
+
+func TestCheckMigrations(t *testing.T) {
+	// Checks run concurrently from readiness probes
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- healthRepo.CheckMigrations(context.Background())
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Expected database to be migrated: %v", err)
+		}
+	}
+}
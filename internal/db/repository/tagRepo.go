@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/metrics"
+	"music-lib/internal/utils"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+type ITagRepo interface {
+	GetAll(ctx context.Context) ([]models.TagCount, error)
+	AddToSong(ctx context.Context, songID int, tags []string) error
+	RemoveFromSong(ctx context.Context, songID int, tag string) error
+}
+
+type TagRepository struct {
+	db *sqlx.DB
+}
+
+func NewTagRepository(db *sqlx.DB) ITagRepo {
+	return &TagRepository{db}
+}
+
+// GetAll returns tags used in the library with the number of songs labeled with them, most used first.
+// Tags are shared between libraries, tags used only by other libraries or by songs in trash are skipped
+func (r *TagRepository) GetAll(ctx context.Context) ([]models.TagCount, error) {
+	defer metrics.ObserveQuery("tag", "GetAll", time.Now())
+	tags := []models.TagCount{}
+	query := `
+        SELECT tag.name, COUNT(song.id) AS count
+        FROM tag
+        JOIN song_tag st ON st.tag_id = tag.id
+        JOIN song ON song.id = st.song_id AND song.library=$1 AND song.deleted_at IS NULL
+        GROUP BY tag.name
+        ORDER BY count DESC, tag.name ASC
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	err := r.db.SelectContext(ctx, &tags, query, utils.LibraryFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// AddToSong labels the song with the tags, unknown tags are created and tags already on the song are skipped
+func (r *TagRepository) AddToSong(ctx context.Context, songID int, tags []string) error {
+	defer metrics.ObserveQuery("tag", "AddToSong", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM song WHERE id=$1 AND library=$2 AND deleted_at IS NULL)`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &exists, query, songID, utils.LibraryFromContext(ctx)); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("not found error: song with id %d doesn't exist", songID)
+	}
+	query = `
+        INSERT INTO tag(name) SELECT unnest($1::text[])
+        ON CONFLICT (name) DO NOTHING
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, pq.Array(tags)); err != nil {
+		return err
+	}
+	query = `
+        INSERT INTO song_tag(song_id, tag_id)
+        SELECT $1, id FROM tag WHERE name = ANY($2)
+        ON CONFLICT DO NOTHING
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, songID, pq.Array(tags)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveFromSong removes the tag from the song, the tag is deleted when no song uses it anymore
+func (r *TagRepository) RemoveFromSong(ctx context.Context, songID int, tag string) error {
+	defer metrics.ObserveQuery("tag", "RemoveFromSong", time.Now())
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tagID int
+	query := `
+        DELETE FROM song_tag st USING tag
+        WHERE tag.id = st.tag_id AND st.song_id=$1 AND tag.name=$2
+            AND st.song_id IN (SELECT id FROM song WHERE library=$3)
+        RETURNING st.tag_id
+        `
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if err := tx.GetContext(ctx, &tagID, query, songID, tag, utils.LibraryFromContext(ctx)); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("not found error: song with id %d has no tag %s", songID, tag)
+		}
+		return err
+	}
+	query = `DELETE FROM tag WHERE id=$1 AND NOT EXISTS(SELECT 1 FROM song_tag WHERE tag_id=$1)`
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	if _, err := tx.ExecContext(ctx, query, tagID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"music-lib/internal/tracing"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startQuery logs the statement and starts its span named after the repository method,
+// the span is finished with endQuery
+func startQuery(ctx context.Context, name, query string) trace.Span {
+	log.Ctx(ctx).Debug().Msgf("Running query: %s", query)
+	_, span := tracing.Start(ctx, name,
+		semconv.DBSystemPostgreSQL, semconv.DBQueryText(query))
+	return span
+}
+
+// endQuery records the number of rows returned or affected by the statement or its error and finishes the span.
+// No rows is not an error of the statement
+func endQuery(span trace.Span, rows int64, err error) {
+	if err == sql.ErrNoRows {
+		rows, err = 0, nil
+	}
+	if err != nil {
+		tracing.Fail(span, err)
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows", rows))
+	}
+	span.End()
+}
+
+// affected returns the number of rows affected by the statement, 0 if it failed
+func affected(res sql.Result) int64 {
+	if res == nil {
+		return 0
+	}
+	count, _ := res.RowsAffected()
+	return count
+}
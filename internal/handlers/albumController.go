@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type AlbumController struct {
+	AlbumService services.IAlbumService
+	Timeout      time.Duration
+}
+
+func NewAlbumController(albumS services.IAlbumService, cfg *config.Config) *AlbumController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &AlbumController{albumS, timeout}
+}
+
+// @Summary      Create a new album
+// @Description  Create an album of the artist, the artist is created if it doesn't exist
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        album body utils.AlbumRequest true "Album request"
+// @Success      201  {object}  utils.Response{message=string, data=models.Album} "Album created"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      409  {object}  utils.Response{message=string} "Album already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /albums [post]
+func (ac *AlbumController) CreateAlbum(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album from request
+	aReq := new(utils.AlbumRequest)
+	if err := c.Bind(aReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(aReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	album := albumFromRequest(aReq)
+	if err := ac.AlbumService.CreateAlbum(ctx, album); err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "Album created", Data: album})
+}
+
+// @Summary      Get albums
+// @Description  Retrieve albums without tracks with pagination
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Album} "Albums received"
+// @Failure      400  {object}  utils.Response{message=string} "Error while parsing query params"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /albums [get]
+func (ac *AlbumController) GetAlbums(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Parse query params
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	albums, err := ac.AlbumService.GetAlbums(ctx, p, l)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Albums received", Data: albums})
+}
+
+// @Summary      Get an album by ID
+// @Description  Retrieve album details with tracks ordered by disc and track number, every track embeds its song
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Album ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Album} "Album received"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid album ID"
+// @Failure      404  {object}  utils.Response{message=string} "Album not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /albums/{id} [get]
+func (ac *AlbumController) GetAlbum(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid album id %s", c.Param("id"))})
+	}
+	album, err := ac.AlbumService.GetAlbum(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Album received", Data: album})
+}
+
+// @Summary      Update an album
+// @Description  Replace title, artist, release date and cover of the album, tracks are kept
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        id     path     int  true  "Album ID"
+// @Param        album  body     utils.AlbumRequest  true  "Album details"
+// @Success      200  {object}  utils.Response{message=string, data=models.Album} "Album updated"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid album ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Album not found"
+// @Failure      409  {object}  utils.Response{message=string} "Album already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /albums/{id} [put]
+func (ac *AlbumController) PutAlbum(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid album id %s", c.Param("id"))})
+	}
+	// Extract album details from request
+	aReq := new(utils.AlbumRequest)
+	if err := c.Bind(aReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(aReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	album := albumFromRequest(aReq)
+	album.ID = &id
+	if err := ac.AlbumService.UpdateAlbum(ctx, album); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Album updated", Data: album})
+}
+
+// @Summary      Delete an album by ID
+// @Description  Remove album and its track listing, songs are kept
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Album ID"
+// @Success      200  {object}  utils.Response{message=string} "Album deleted"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid album ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Album not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /albums/{id} [delete]
+func (ac *AlbumController) DeleteAlbum(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid album id %s", c.Param("id"))})
+	}
+	if err := ac.AlbumService.DeleteAlbum(ctx, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Album deleted"})
+}
+
+// @Summary      Add a track to an album
+// @Description  Put the song on the album at the given disc and track number
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        id     path     int  true  "Album ID"
+// @Param        track  body     utils.AlbumTrackRequest  true  "Track details"
+// @Success      201  {object}  utils.Response{message=string, data=models.AlbumTrack} "Track added"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid album ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Album or song not found"
+// @Failure      409  {object}  utils.Response{message=string} "Track number is taken or song is already on the album"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /albums/{id}/tracks [post]
+func (ac *AlbumController) AddTrack(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid album id %s", c.Param("id"))})
+	}
+	// Extract track details from request
+	tReq := new(utils.AlbumTrackRequest)
+	if err := c.Bind(tReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(tReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	track := &models.AlbumTrack{
+		AlbumID:     id,
+		SongID:      tReq.SongID,
+		DiscNumber:  tReq.DiscNumber,
+		TrackNumber: tReq.TrackNumber,
+	}
+	if err := ac.AlbumService.AddTrack(ctx, track); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "Track added", Data: track})
+}
+
+// @Summary      Remove a track from an album
+// @Description  Remove the song from the album track listing, the song itself is kept
+// @Tags         Albums
+// @Accept       json
+// @Produce      json
+// @Param        id      path     int  true  "Album ID"
+// @Param        songId  path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string} "Track removed"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid album or song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song is not on the album"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /albums/{id}/tracks/{songId} [delete]
+func (ac *AlbumController) RemoveTrack(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract album and song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid album id %s", c.Param("id"))})
+	}
+	songID, err := strconv.Atoi(c.Param("songId"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("songId"))})
+	}
+	if err := ac.AlbumService.RemoveTrack(ctx, id, songID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Track removed"})
+}
+
+func albumFromRequest(aReq *utils.AlbumRequest) *models.Album {
+	return &models.Album{
+		Title:       aReq.Title,
+		Artist:      aReq.Group,
+		ReleaseDate: aReq.ReleaseDate,
+		CoverURL:    aReq.CoverURL,
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/middlewares"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type APIKeyController struct {
+	APIKeyService services.IAPIKeyService
+	Timeout       time.Duration
+}
+
+func NewAPIKeyController(apiKeyS services.IAPIKeyService, cfg *config.Config) *APIKeyController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &APIKeyController{apiKeyS, timeout}
+}
+
+// @Summary      Issue an API key
+// @Description  Issue a key for service clients to send in X-API-Key header. The key is returned only once, store it securely.
+// @Description  Keys issued with a library can access only that library.
+// @Description  Keys bound to a library can issue keys only for that library, it is used if not set.
+// @Tags         API keys
+// @Accept       json
+// @Produce      json
+// @Param        key body utils.APIKeyRequest true "API key request"
+// @Success      201  {object}  utils.Response{message=string, data=models.IssuedAPIKey} "API key issued"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Admin scope required or library is not accessible"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /admin/api-keys [post]
+func (kc *APIKeyController) IssueKey(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), kc.Timeout)
+	defer cancel()
+	// Extract key details from request
+	kReq := new(utils.APIKeyRequest)
+	if err := c.Bind(kReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(kReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	if kReq.Library != "" && !utils.IsLibraryName(kReq.Library) {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: []string{"Library: library"}})
+	}
+	// Keys bound to a library can't issue keys for other libraries
+	if bound, ok := c.Get(middlewares.BoundLibraryKey).(string); ok {
+		if kReq.Library != "" && kReq.Library != bound {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.Response{Message: "library " + kReq.Library + " is not accessible with these credentials"})
+		}
+		kReq.Library = bound
+	}
+	ttl := time.Duration(kReq.ExpiresIn) * time.Hour
+	key, err := kc.APIKeyService.IssueKey(ctx, kReq.Name, kReq.Scopes, kReq.Library, ttl)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "API key issued", Data: key})
+}
+
+// @Summary      Get API keys
+// @Description  Retrieve all API keys including revoked and expired ones, newest first. Keys themselves are not returned.
+// @Description  Keys bound to a library receive only keys of that library.
+// @Tags         API keys
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response{message=string, data=[]models.APIKey} "API keys received"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Admin scope required"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /admin/api-keys [get]
+func (kc *APIKeyController) GetKeys(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), kc.Timeout)
+	defer cancel()
+	bound, _ := c.Get(middlewares.BoundLibraryKey).(string)
+	keys, err := kc.APIKeyService.GetKeys(ctx, bound)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "API keys received", Data: keys})
+}
+
+// @Summary      Revoke an API key
+// @Description  Revoke the key, requests with it are rejected from now on.
+// @Description  Keys bound to a library can revoke only keys of that library.
+// @Tags         API keys
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "API key ID"
+// @Success      200  {object}  utils.Response{message=string} "API key revoked"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid API key ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Admin scope required"
+// @Failure      404  {object}  utils.Response{message=string} "API key not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /admin/api-keys/{id} [delete]
+func (kc *APIKeyController) RevokeKey(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), kc.Timeout)
+	defer cancel()
+	// Extract key id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid API key id %s", c.Param("id"))})
+	}
+	bound, _ := c.Get(middlewares.BoundLibraryKey).(string)
+	if err := kc.APIKeyService.RevokeKey(ctx, id, bound); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "API key revoked"})
+}
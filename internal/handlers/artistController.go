@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ArtistController struct {
+	ArtistService services.IArtistService
+	Timeout       time.Duration
+}
+
+func NewArtistController(artistS services.IArtistService, cfg *config.Config) *ArtistController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &ArtistController{artistS, timeout}
+}
+
+// @Summary      Create a new artist
+// @Description  Create an artist with canonical name, aliases and country. Songs refer to the artist by name or any alias.
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        artist body utils.ArtistRequest true "Artist request"
+// @Success      201  {object}  utils.Response{message=string, data=models.Artist} "Artist created"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      409  {object}  utils.Response{message=string} "Artist name or alias already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /artists [post]
+func (ac *ArtistController) CreateArtist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract artist from request
+	aReq := new(utils.ArtistRequest)
+	if err := c.Bind(aReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(aReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	artist := artistFromRequest(aReq)
+	if err := ac.ArtistService.CreateArtist(ctx, artist); err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "Artist created", Data: artist})
+}
+
+// @Summary      Get artists
+// @Description  Retrieve artists ordered by name with pagination
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Artist} "Artists received"
+// @Failure      400  {object}  utils.Response{message=string} "Error while parsing query params"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /artists [get]
+func (ac *ArtistController) GetArtists(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Parse query params
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	artists, err := ac.ArtistService.GetArtists(ctx, p, l)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Artists received", Data: artists})
+}
+
+// @Summary      Get an artist by ID
+// @Description  Retrieve artist details by ID
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Artist ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Artist} "Artist received"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid artist ID"
+// @Failure      404  {object}  utils.Response{message=string} "Artist not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /artists/{id} [get]
+func (ac *ArtistController) GetArtist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract artist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid artist id %s", c.Param("id"))})
+	}
+	artist, err := ac.ArtistService.GetArtist(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Artist received", Data: artist})
+}
+
+// @Summary      Update an artist
+// @Description  Replace name, aliases and country of the artist. Songs of the renamed artist are updated, the old name is kept as an alias.
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        id      path     int  true  "Artist ID"
+// @Param        artist  body     utils.ArtistRequest  true  "Artist details"
+// @Success      200  {object}  utils.Response{message=string, data=models.Artist} "Artist updated"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid artist ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Artist not found"
+// @Failure      409  {object}  utils.Response{message=string} "Artist name or alias already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /artists/{id} [put]
+func (ac *ArtistController) PutArtist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract artist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid artist id %s", c.Param("id"))})
+	}
+	// Extract artist details from request
+	aReq := new(utils.ArtistRequest)
+	if err := c.Bind(aReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(aReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	artist := artistFromRequest(aReq)
+	artist.ID = &id
+	if err := ac.ArtistService.UpdateArtist(ctx, artist); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Artist updated", Data: artist})
+}
+
+// @Summary      Delete an artist by ID
+// @Description  Remove artist from database, artists with songs or albums can't be deleted
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Artist ID"
+// @Success      200  {object}  utils.Response{message=string} "Artist deleted"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid artist ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Artist not found"
+// @Failure      409  {object}  utils.Response{message=string} "Artist has songs or albums"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /artists/{id} [delete]
+func (ac *ArtistController) DeleteArtist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract artist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid artist id %s", c.Param("id"))})
+	}
+	if err := ac.ArtistService.DeleteArtist(ctx, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "conflict") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Artist deleted"})
+}
+
+// @Summary      Get songs of an artist
+// @Description  Retrieve songs of the artist with pagination
+// @Tags         Artists
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int     true   "Artist ID"
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Song} "Songs received"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid artist ID or query params"
+// @Failure      404  {object}  utils.Response{message=string} "Artist not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /artists/{id}/songs [get]
+func (ac *ArtistController) GetArtistSongs(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.Timeout)
+	defer cancel()
+	// Extract artist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid artist id %s", c.Param("id"))})
+	}
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	songs, err := ac.ArtistService.GetArtistSongs(ctx, id, p, l)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Songs received", Data: songs})
+}
+
+func artistFromRequest(aReq *utils.ArtistRequest) *models.Artist {
+	artist := &models.Artist{
+		Name:    aReq.Name,
+		Aliases: aReq.Aliases,
+	}
+	if aReq.Country != "" {
+		artist.Country = &aReq.Country
+	}
+	return artist
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FavoriteController struct {
+	FavoriteService services.IFavoriteService
+	Timeout         time.Duration
+}
+
+func NewFavoriteController(favoriteS services.IFavoriteService, cfg *config.Config) *FavoriteController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &FavoriteController{favoriteS, timeout}
+}
+
+// @Summary      Get my favorite songs
+// @Description  Retrieve favorite songs of the requesting user with the songs embedded, most recently added first. Songs in trash are left out.
+// @Tags         Favorites
+// @Accept       json
+// @Produce      json
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Favorite} "Favorites received"
+// @Failure      400  {object}  utils.Response{message=string} "Error while parsing query params"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Read scope required"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /me/favorites [get]
+func (fc *FavoriteController) GetFavorites(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), fc.Timeout)
+	defer cancel()
+	// Parse query params
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	favorites, err := fc.FavoriteService.GetFavorites(ctx, p, l)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Favorites received", Data: favorites})
+}
+
+// @Summary      Add a song to my favorites
+// @Description  Add the song to favorites of the requesting user, adding a favorite song again changes nothing
+// @Tags         Favorites
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Favorite} "Favorite added"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Read scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /me/favorites/{id} [put]
+func (fc *FavoriteController) AddFavorite(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), fc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	favorite, err := fc.FavoriteService.AddFavorite(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Favorite added", Data: favorite})
+}
+
+// @Summary      Remove a song from my favorites
+// @Description  Remove the song from favorites of the requesting user
+// @Tags         Favorites
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string} "Favorite removed"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Read scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song is not a favorite"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /me/favorites/{id} [delete]
+func (fc *FavoriteController) RemoveFavorite(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), fc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	if err := fc.FavoriteService.RemoveFavorite(ctx, id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Favorite removed"})
+}
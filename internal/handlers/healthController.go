@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthController struct {
+	HealthService services.IHealthService
+}
+
+func NewHealthController(healthS services.IHealthService) *HealthController {
+	return &HealthController{healthS}
+}
+
+// Live responds to liveness probes while the process is up, dependencies are not checked.
+// Probes are served outside of the API base path, so they are not in Swagger documentation
+func (hc *HealthController) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, utils.Response{Message: "Service is alive"})
+}
+
+// Ready responds to readiness probes with the status of every dependency, 503 if any of them is down
+func (hc *HealthController) Ready(c echo.Context) error {
+	report := hc.HealthService.Ready(c.Request().Context())
+	if report.Status != services.HealthUp {
+		return c.JSON(
+			http.StatusServiceUnavailable,
+			utils.Response{Message: "Service is not ready", Data: report})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Service is ready", Data: report})
+}
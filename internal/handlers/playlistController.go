@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type PlaylistController struct {
+	PlaylistService services.IPlaylistService
+	Timeout         time.Duration
+}
+
+func NewPlaylistController(playlistS services.IPlaylistService, cfg *config.Config) *PlaylistController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &PlaylistController{playlistS, timeout}
+}
+
+// @Summary      Create a new playlist
+// @Description  Create a playlist owned by the requesting user, playlists are private unless visibility is public
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        playlist body utils.PlaylistRequest true "Playlist request"
+// @Success      201  {object}  utils.Response{message=string, data=models.Playlist} "Playlist created"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists [post]
+func (pc *PlaylistController) CreatePlaylist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist from request
+	pReq := new(utils.PlaylistRequest)
+	if err := c.Bind(pReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(pReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	playlist := playlistFromRequest(pReq)
+	if err := pc.PlaylistService.CreatePlaylist(ctx, playlist); err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "Playlist created", Data: playlist})
+}
+
+// @Summary      Get playlists
+// @Description  Retrieve public playlists and private playlists of the requesting user without entries, most recently updated first
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Playlist} "Playlists received"
+// @Failure      400  {object}  utils.Response{message=string} "Error while parsing query params"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /playlists [get]
+func (pc *PlaylistController) GetPlaylists(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Parse query params
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	playlists, err := pc.PlaylistService.GetPlaylists(ctx, p, l)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Playlists received", Data: playlists})
+}
+
+// @Summary      Get a playlist by ID
+// @Description  Retrieve playlist with its entries ordered by position and their songs embedded. Songs in trash are left out.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Playlist ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Playlist} "Playlist received"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid playlist ID"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /playlists/{id} [get]
+func (pc *PlaylistController) GetPlaylist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	playlist, err := pc.PlaylistService.GetPlaylist(ctx, id)
+	if err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Playlist received", Data: playlist})
+}
+
+// @Summary      Update a playlist
+// @Description  Replace name, description and visibility of a playlist owned by the requesting user
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id        path     int  true  "Playlist ID"
+// @Param        playlist  body     utils.PlaylistRequest  true  "Playlist details"
+// @Success      200  {object}  utils.Response{message=string, data=models.Playlist} "Playlist updated"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid playlist ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or playlist is owned by another user"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists/{id} [put]
+func (pc *PlaylistController) PutPlaylist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	// Extract playlist details from request
+	pReq := new(utils.PlaylistRequest)
+	if err := c.Bind(pReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(pReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	playlist := playlistFromRequest(pReq)
+	playlist.ID = &id
+	if err := pc.PlaylistService.UpdatePlaylist(ctx, playlist); err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Playlist updated", Data: playlist})
+}
+
+// @Summary      Delete a playlist by ID
+// @Description  Remove playlist owned by the requesting user with all its entries, the songs are kept
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Playlist ID"
+// @Success      200  {object}  utils.Response{message=string} "Playlist deleted"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid playlist ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or playlist is owned by another user"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists/{id} [delete]
+func (pc *PlaylistController) DeletePlaylist(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	if err := pc.PlaylistService.DeletePlaylist(ctx, id); err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Playlist deleted"})
+}
+
+// @Summary      Add a song to a playlist
+// @Description  Insert the song at the given position, entries from that position on move down.
+// @Description  The song is appended if position is not set or past the end. The same song can be added more than once.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id     path     int  true  "Playlist ID"
+// @Param        entry  body     utils.PlaylistEntryRequest  true  "Entry details"
+// @Success      201  {object}  utils.Response{message=string, data=models.Playlist} "Entry added"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid playlist ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or playlist is owned by another user"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist or song not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists/{id}/entries [post]
+func (pc *PlaylistController) AddEntry(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	// Extract entry details from request
+	eReq := new(utils.PlaylistEntryRequest)
+	if err := c.Bind(eReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(eReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	entry := &models.PlaylistEntry{
+		PlaylistID: id,
+		SongID:     eReq.SongID,
+		Position:   eReq.Position,
+	}
+	playlist, err := pc.PlaylistService.AddEntry(ctx, entry)
+	if err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(
+		http.StatusCreated,
+		utils.Response{Message: "Entry added", Data: playlist})
+}
+
+// @Summary      Move a playlist entry
+// @Description  Move the entry to the given position, entries in between shift by one. Position past the end moves the entry last.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id       path     int  true  "Playlist ID"
+// @Param        entryId  path     int  true  "Entry ID"
+// @Param        entry    body     utils.PlaylistMoveRequest  true  "New position"
+// @Success      200  {object}  utils.Response{message=string, data=models.Playlist} "Entry moved"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid playlist or entry ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or playlist is owned by another user"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist or entry not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists/{id}/entries/{entryId} [patch]
+func (pc *PlaylistController) MoveEntry(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist and entry id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	entryID, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid entry id %s", c.Param("entryId"))})
+	}
+	// Extract new position from request
+	mReq := new(utils.PlaylistMoveRequest)
+	if err := c.Bind(mReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(mReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	entry := &models.PlaylistEntry{
+		ID:         entryID,
+		PlaylistID: id,
+		Position:   mReq.Position,
+	}
+	playlist, err := pc.PlaylistService.MoveEntry(ctx, entry)
+	if err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Entry moved", Data: playlist})
+}
+
+// @Summary      Remove a playlist entry
+// @Description  Remove the entry from the playlist, entries after it move up. The song itself is kept.
+// @Tags         Playlists
+// @Accept       json
+// @Produce      json
+// @Param        id       path     int  true  "Playlist ID"
+// @Param        entryId  path     int  true  "Entry ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Playlist} "Entry removed"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid playlist or entry ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or playlist is owned by another user"
+// @Failure      404  {object}  utils.Response{message=string} "Playlist or entry not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /playlists/{id}/entries/{entryId} [delete]
+func (pc *PlaylistController) RemoveEntry(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), pc.Timeout)
+	defer cancel()
+	// Extract playlist and entry id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid playlist id %s", c.Param("id"))})
+	}
+	entryID, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid entry id %s", c.Param("entryId"))})
+	}
+	playlist, err := pc.PlaylistService.RemoveEntry(ctx, id, entryID)
+	if err != nil {
+		return playlistError(c, err)
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Entry removed", Data: playlist})
+}
+
+// playlistError maps errors of playlist service to response status
+func playlistError(c echo.Context, err error) error {
+	if strings.Contains(err.Error(), "not found") {
+		return c.JSON(
+			http.StatusNotFound,
+			utils.Response{Message: err.Error()})
+	}
+	if strings.Contains(err.Error(), "forbidden") {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.Response{Message: err.Error()})
+}
+
+func playlistFromRequest(pReq *utils.PlaylistRequest) *models.Playlist {
+	return &models.Playlist{
+		Name:        pReq.Name,
+		Description: pReq.Description,
+		Visibility:  pReq.Visibility,
+	}
+}
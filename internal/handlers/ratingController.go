@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type RatingController struct {
+	RatingService services.IRatingService
+	Timeout       time.Duration
+}
+
+func NewRatingController(ratingS services.IRatingService, cfg *config.Config) *RatingController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &RatingController{ratingS, timeout}
+}
+
+// @Summary      Rate a song
+// @Description  Rate the song from 1 to 5 on behalf of the requesting user, the previous rating of the user is replaced.
+// @Description  Ratings are not recorded in song history.
+// @Tags         Ratings
+// @Accept       json
+// @Produce      json
+// @Param        id      path     int  true  "Song ID"
+// @Param        rating  body     utils.RatingRequest  true  "Rating"
+// @Success      200  {object}  utils.Response{message=string, data=models.Song} "Song rated"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid song ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Read scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/rating [put]
+func (rc *RatingController) RateSong(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), rc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	// Extract rating from request
+	rReq := new(utils.RatingRequest)
+	if err := c.Bind(rReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(rReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	song, err := rc.RatingService.RateSong(ctx, id, rReq.Rating)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song rated", Data: song})
+}
+
+// @Summary      Remove my rating of a song
+// @Description  Remove rating of the song given by the requesting user
+// @Tags         Ratings
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Song} "Rating removed"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Read scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song is not rated by the user"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/rating [delete]
+func (rc *RatingController) RemoveRating(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), rc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	song, err := rc.RatingService.RemoveRating(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Rating removed", Data: song})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
 	"music-lib/internal/config"
 	"music-lib/internal/db/models"
 	"music-lib/internal/db/repository"
@@ -21,30 +22,41 @@ import (
 type SongController struct {
 	SongService      services.ISongService
 	MusicInfoService services.IMusicInfoService
+	PolicyService    services.IPolicyService
 	Timeout          time.Duration
+	StrictPut        bool
 }
 
 func NewSongController(
 	songS services.ISongService,
 	musicInfoS services.IMusicInfoService,
+	policyS services.IPolicyService,
 	cfg *config.Config) *SongController {
 
 	timeout := time.Duration(cfg.Server.Timeout) * time.Second
 
-	return &SongController{songS, musicInfoS, timeout}
+	return &SongController{songS, musicInfoS, policyS, timeout, cfg.Server.StrictPut}
 }
 
 // @Summary      Create a new song
 // @Description  Create a new song by providing the group and song name. The song details are fetched from an external API.
+// @Description  Featured and contributing artists can be credited with roles, the group is always the primary artist.
 // @Tags         Songs
 // @Accept       json
 // @Produce      json
 // @Param        song body utils.SongPostRequest true "Song request"
+// @Param        Idempotency-Key header string false "Unique key, repeated requests with the same key replay the original response"
 // @Success      201  {object}  utils.Response{message=string, data=models.Song} "Song created"
 // @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to create songs"
 // @Failure      404  {object}  utils.Response{message=string} "Song not found"
-// @Failure      409  {object}  utils.Response{message=string} "Song already exists"
+// @Failure      409  {object}  utils.Response{message=string} "Song already exists or request with the same idempotency key is in progress"
+// @Failure      422  {object}  utils.Response{message=string} "Idempotency key was used with a different request"
+// @Failure      429  {object}  utils.Response{message=string} "Rate limit of the client exceeded, see Retry-After header"
 // @Failure      500  {object}  utils.Response{message=string} "External API error or internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /songs [post]
 func (sc *SongController) CreateSong(c echo.Context) error {
 	// New context with timeout
@@ -65,8 +77,11 @@ func (sc *SongController) CreateSong(c echo.Context) error {
 			http.StatusBadRequest,
 			utils.Response{Message: "invalid request", Data: errors})
 	}
+	if err := sc.PolicyService.Authorize(ctx, services.ActionSongCreate); err != nil {
+		return c.JSON(http.StatusForbidden, utils.Response{Message: err.Error()})
+	}
 	// Fetch song details from external service
-	songDetail, err := sc.MusicInfoService.GetSongInfo(songRequest.Group, songRequest.Song)
+	songDetail, err := sc.MusicInfoService.GetSongInfo(ctx, songRequest.Group, songRequest.Song)
 	if err != nil {
 		if err.Error() == "404" {
 			return c.JSON(
@@ -84,6 +99,8 @@ func (sc *SongController) CreateSong(c echo.Context) error {
 		Lyrics:      songDetail.Text,
 		URL:         songDetail.Link,
 		ReleaseDate: songDetail.ReleaseDate,
+		Credits:     models.CreditsFromRequest(songRequest.Credits),
+		Genres:      models.NormalizeLabels(songRequest.Genres),
 	}
 	if err := sc.SongService.CreateSong(ctx, song); err != nil {
 		if strings.Contains(err.Error(), "duplicate") {
@@ -140,12 +157,17 @@ func (sc *SongController) GetSong(c echo.Context) error {
 }
 
 // @Summary      Get songs with optional filtering and pagination
-// @Description  Retrieve a list of songs with optional filters such as group, song name, and date range, and supports pagination with page and limit parameters.
+// @Description  Retrieve a list of songs with optional filters such as group, song name, date range, genre and tags, and supports pagination with page and limit parameters.
+// @Description  Songs are sorted by id unless sort is set, unrated songs are the last when sorted by average rating.
 // @Tags         Songs
 // @Accept       json
 // @Produce      json
-// @Param        group   query     string  false  "Filter by group/artist name"
+// @Param        group   query     string  false  "Filter by group/artist name or alias, songs crediting the artist match too"
+// @Param        genre   query     string  false  "Filter by genre"
+// @Param        tag     query     []string  false  "Filter by tags, repeated or comma separated"  collectionFormat(multi)
+// @Param        tag_match  query  string  false  "Match songs with any or all of the tags, default any"  Enums(any, all)
 // @Param        song    query     string  false  "Filter by song name"
+// @Param        sort    query     string  false  "Sort by id or average rating, - for descending order, default id"  Enums(id, -id, rating, -rating)
 // @Param        after   query     string  false  "Filter by songs released after date (dd.mm.yyyy)"
 // @Param        before  query     string  false  "Filter by songs released before date (dd.mm.yyyy)"
 // @Param        page    query     int     false  "Page number for pagination, default 1"
@@ -180,15 +202,24 @@ func (sc *SongController) GetSongs(c echo.Context) error {
 
 // @Summary      Partially update a song
 // @Description  Update one or more fields of an existing song by providing the song ID and the fields to update.
+// @Description  With application/json empty fields are ignored. Send application/merge-patch+json (RFC 7396)
+// @Description  or application/json-patch+json (RFC 6902) to clear fields, the patched song is validated before saving.
 // @Tags         Songs
 // @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
 // @Produce      json
 // @Param        id    path     int  true  "Song ID"
 // @Param        song  body     utils.SongPatchRequest  true  "Fields to update"
 // @Success      200  {object}  utils.Response{message=string, data=models.Song} "Song updated"
-// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID or request"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid song ID, request or patch"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to update the song or its lyrics"
 // @Failure      404  {object}  utils.Response{message=string} "Song not found"
+// @Failure      409  {object}  utils.Response{message=string} "JSON Patch test operation failed or song already exists"
 // @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /songs/{id} [patch]
 func (sc *SongController) PatchSong(c echo.Context) error {
 	// New context with timeout
@@ -201,6 +232,10 @@ func (sc *SongController) PatchSong(c echo.Context) error {
 			http.StatusBadRequest,
 			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
 	}
+	contentType := strings.TrimSpace(strings.Split(c.Request().Header.Get(echo.HeaderContentType), ";")[0])
+	if contentType == utils.MIMEMergePatch || contentType == utils.MIMEJSONPatch {
+		return sc.patchSongDocument(ctx, c, id, contentType)
+	}
 	// Extract song details from request
 	sReq := new(utils.SongPatchRequest)
 	if err := c.Bind(sReq); err != nil {
@@ -218,19 +253,6 @@ func (sc *SongController) PatchSong(c echo.Context) error {
 			http.StatusBadRequest,
 			utils.Response{Message: "invalid request", Data: errors})
 	}
-	// Get original song from db
-	song, err := sc.SongService.GetSong(ctx, id)
-	if err != nil {
-		log.Logger.Error().Msgf(err.Error())
-		if strings.Contains(err.Error(), "not found") {
-			return c.JSON(
-				http.StatusNotFound,
-				utils.Response{Message: err.Error()})
-		}
-		return c.JSON(
-			http.StatusInternalServerError,
-			utils.Response{Message: err.Error()})
-	}
 	// Update song in db
 	newSong := &models.Song{
 		ID:          &id,
@@ -239,28 +261,89 @@ func (sc *SongController) PatchSong(c echo.Context) error {
 		Lyrics:      sReq.Lyrics,
 		URL:         sReq.URL,
 		ReleaseDate: sReq.ReleaseDate,
+		Credits:     models.CreditsFromRequest(sReq.Credits),
+		Genres:      models.NormalizeLabels(sReq.Genres),
+	}
+	updatedSong, err := sc.SongService.UpdateSong(ctx, id, newSong, sc.authorizeUpdate(ctx))
+	if err != nil {
+		return songUpdateError(c, err)
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song updated", Data: updatedSong})
+}
+
+// patchSongDocument applies JSON Merge Patch or JSON Patch from the request body to the song
+func (sc *SongController) patchSongDocument(ctx context.Context, c echo.Context, id int, patchType string) error {
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: err.Error()})
 	}
-	updatedSong, err := sc.SongService.UpdateSong(ctx, song, newSong)
+	updatedSong, err := sc.SongService.PatchSong(ctx, id, patchType, patch, sc.authorizeUpdate(ctx))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, utils.Response{Message: err.Error()})
+		return songUpdateError(c, err)
 	}
 	return c.JSON(
 		http.StatusOK,
 		utils.Response{Message: "Song updated", Data: updatedSong})
 }
 
+// authorizeUpdate checks updates of songs with the role of the request
+func (sc *SongController) authorizeUpdate(ctx context.Context) services.AuthorizeUpdate {
+	return func(song, newSong *models.Song) error {
+		return sc.PolicyService.AuthorizeSongUpdate(ctx, song, newSong)
+	}
+}
+
+// songUpdateError responds with the status of the error of updating or patching a song
+func songUpdateError(c echo.Context, err error) error {
+	if vErrs, ok := err.(validator.ValidationErrors); ok {
+		var errors []string
+		for _, e := range vErrs {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid song after patch", Data: errors})
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		status = http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid patch"):
+		status = http.StatusBadRequest
+	case strings.Contains(err.Error(), "forbidden"):
+		status = http.StatusForbidden
+	case strings.Contains(err.Error(), "conflict"), strings.Contains(err.Error(), "duplicate"):
+		status = http.StatusConflict
+	}
+	return c.JSON(status, utils.Response{Message: err.Error()})
+}
+
 // @Summary      Fully update a song or create a new one
-// @Description  Replace an existing song by providing the song ID and the full song data. If the song doesn't exist, create a new one.
+// @Description  Replace an existing song by providing the song ID and the full song data. If the song doesn't exist, create a new one with this ID.
+// @Description  When the server runs with strict-put enabled, missing songs are not created and 404 is returned.
+// @Description  Replacing a song in trash restores it, which needs the role of restoring songs as well.
 // @Tags         Songs
 // @Accept       json
 // @Produce      json
 // @Param        id    path     int  true  "Song ID"
 // @Param        song  body     utils.SongPutRequest  true  "Full song details"
+// @Param        Idempotency-Key header string false "Unique key, repeated requests with the same key replay the original response"
 // @Success      200  {object}  utils.Response{message=string, data=models.Song} "Song updated"
 // @Success      201  {object}  utils.Response{message=string, data=models.Song} "Song created"
 // @Failure      400  {object}  utils.Response{message=string} "Invalid song ID or request"
-// @Failure      409  {object}  utils.Response{message=string} "Song already exists"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to create, update or restore the song or edit its lyrics"
+// @Failure      404  {object}  utils.Response{message=string} "Song not found, only with strict-put"
+// @Failure      409  {object}  utils.Response{message=string} "Song already exists or request with the same idempotency key is in progress"
+// @Failure      422  {object}  utils.Response{message=string} "Idempotency key was used with a different request"
 // @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /songs/{id} [put]
 func (sc *SongController) PutSong(c echo.Context) error {
 	// New context with timeout
@@ -268,7 +351,7 @@ func (sc *SongController) PutSong(c echo.Context) error {
 	defer cancel()
 	// Extract song id from request
 	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
+	if err != nil || id < 1 {
 		return c.JSON(
 			http.StatusBadRequest,
 			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
@@ -280,7 +363,7 @@ func (sc *SongController) PutSong(c echo.Context) error {
 			http.StatusBadRequest,
 			utils.Response{Message: err.Error()})
 	}
-	log.Logger.Debug().Msgf("SongPutRequest date: %s", sReq.ReleaseDate.Format("02.01.2006"))
+	log.Ctx(ctx).Debug().Msgf("SongPutRequest date: %s", sReq.ReleaseDate.Format("02.01.2006"))
 	if err := validator.New().Struct(sReq); err != nil {
 		var errors []string
 		for _, e := range err.(validator.ValidationErrors) {
@@ -292,54 +375,94 @@ func (sc *SongController) PutSong(c echo.Context) error {
 			utils.Response{Message: "invalid request", Data: errors})
 	}
 	newSong := &models.Song{
+		ID:          &id,
 		Artist:      sReq.Group,
 		Name:        sReq.Song,
 		Lyrics:      sReq.Lyrics,
 		URL:         sReq.URL,
 		ReleaseDate: sReq.ReleaseDate,
+		Credits:     models.CreditsFromRequest(sReq.Credits),
+		Genres:      models.NormalizeLabels(sReq.Genres),
 	}
-	// Get original song from db
-	song, err := sc.SongService.GetSong(ctx, id)
+	if sc.StrictPut {
+		// Update song in db
+		updatedSong, err := sc.SongService.UpdateSong(ctx, id, newSong, sc.authorizeUpdate(ctx))
+		if err != nil {
+			return songUpdateError(c, err)
+		}
+		return c.JSON(
+			http.StatusOK,
+			utils.Response{Message: "Song updated", Data: updatedSong})
+	}
+	// Replacing needs the same role as updating, creating as creating.
+	// Replacing a song in trash restores it, so it needs the role of restoring as well
+	if err := sc.authorizeReplace(ctx, id, newSong); err != nil {
+		if strings.Contains(err.Error(), "forbidden") {
+			return c.JSON(http.StatusForbidden, utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	// Create or replace song with the requested id
+	created, err := sc.SongService.ReplaceSong(ctx, newSong)
 	if err != nil {
-		// Create new song in db
-		if err := sc.SongService.CreateSong(ctx, newSong); err != nil {
-			if strings.Contains(err.Error(), "duplicate") {
-				return c.JSON(
-					http.StatusConflict,
-					utils.Response{Message: err.Error()})
-			}
+		if strings.Contains(err.Error(), "duplicate") {
 			return c.JSON(
-				http.StatusInternalServerError,
+				http.StatusConflict,
 				utils.Response{Message: err.Error()})
 		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	if created {
 		return c.JSON(
 			http.StatusCreated,
 			utils.Response{Message: "Song created", Data: newSong})
-	} else {
-		// Update newSong in db
-		newSong.ID = &id
-		updatedSong, err := sc.SongService.UpdateSong(ctx, song, newSong)
-		if err != nil {
-			return c.JSON(
-				http.StatusInternalServerError,
-				utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song updated", Data: newSong})
+}
+
+// authorizeReplace checks the role of the request for replacing the song with id by newSong,
+// stored song is looked up in trash as well
+func (sc *SongController) authorizeReplace(ctx context.Context, id int, newSong *models.Song) error {
+	song, err := sc.SongService.GetSong(ctx, id)
+	if err == nil {
+		return sc.PolicyService.AuthorizeSongUpdate(ctx, song, newSong)
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		return err
+	}
+	song, err = sc.SongService.GetTrashedSong(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return sc.PolicyService.Authorize(ctx, services.ActionSongCreate)
 		}
-		return c.JSON(
-			http.StatusOK,
-			utils.Response{Message: "Song updated", Data: updatedSong})
+		return err
+	}
+	if err := sc.PolicyService.Authorize(ctx, services.ActionSongRestore); err != nil {
+		return err
 	}
+	return sc.PolicyService.AuthorizeSongUpdate(ctx, song, newSong)
 }
 
 // @Summary      Delete a song by ID
-// @Description  Remove song from database
+// @Description  Move song to trash, it can be restored until it is purged after the retention period
 // @Tags         Songs
 // @Accept       json
 // @Produce      json
 // @Param        id   path     int  true  "Song ID"
 // @Success      200  {object}  utils.Response{message=string} "Song deleted"
 // @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to delete songs"
 // @Failure      404  {object}  utils.Response{message=string} "Song not found"
 // @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
 // @Router       /songs/{id} [delete]
 func (sc *SongController) DeleteSong(c echo.Context) error {
 	// New context with timeout
@@ -352,6 +475,9 @@ func (sc *SongController) DeleteSong(c echo.Context) error {
 			http.StatusBadRequest,
 			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
 	}
+	if err := sc.PolicyService.Authorize(ctx, services.ActionSongDelete); err != nil {
+		return c.JSON(http.StatusForbidden, utils.Response{Message: err.Error()})
+	}
 	// Delete song from db
 	if err := sc.SongService.DeleteSong(ctx, id); err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -365,3 +491,187 @@ func (sc *SongController) DeleteSong(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, utils.Response{Message: "Song deleted"})
 }
+
+// @Summary      Get deleted songs
+// @Description  Retrieve songs in trash, most recently deleted first. Songs are purged permanently after the retention period.
+// @Tags         Songs
+// @Accept       json
+// @Produce      json
+// @Param        page    query     int     false  "Page number for pagination, default 1"
+// @Param        limit   query     int     false  "Limit per page, default 10"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.Song} "Deleted songs received"
+// @Failure      400  {object}  utils.Response{message=string} "Error while parsing query params"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/trash [get]
+func (sc *SongController) GetTrash(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), sc.Timeout)
+	defer cancel()
+	// Parse query params
+	p, l, err := repository.ParsePagination(c.Request().URL.Query())
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "Error while parsing query params: " + err.Error()})
+	}
+	songs, err := sc.SongService.GetTrash(ctx, p, l)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Deleted songs received", Data: songs})
+}
+
+// @Summary      Restore a deleted song
+// @Description  Move song out of trash
+// @Tags         Songs
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string, data=models.Song} "Song restored"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to restore songs"
+// @Failure      404  {object}  utils.Response{message=string} "Song not found in trash"
+// @Failure      409  {object}  utils.Response{message=string} "Song with the same name and artist already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/restore [post]
+func (sc *SongController) RestoreSong(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), sc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	if err := sc.PolicyService.Authorize(ctx, services.ActionSongRestore); err != nil {
+		return c.JSON(http.StatusForbidden, utils.Response{Message: err.Error()})
+	}
+	song, err := sc.SongService.RestoreSong(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song restored", Data: song})
+}
+
+// @Summary      Get history of a song
+// @Description  Retrieve all revisions of the song, oldest first, with the actor, source and changed fields of every revision
+// @Tags         Songs
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int  true  "Song ID"
+// @Success      200  {object}  utils.Response{message=string, data=[]models.SongRevision} "Song history received"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      404  {object}  utils.Response{message=string} "Song history not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /songs/{id}/history [get]
+func (sc *SongController) GetSongHistory(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), sc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	revisions, err := sc.SongService.GetHistory(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song history received", Data: revisions})
+}
+
+// @Summary      Revert a song to a revision
+// @Description  Restore all fields of the song from the given revision. The revert is recorded as a new revision.
+// @Tags         Songs
+// @Accept       json
+// @Produce      json
+// @Param        id        path     int  true  "Song ID"
+// @Param        revision  query    int  true  "Revision number"
+// @Success      200  {object}  utils.Response{message=string, data=models.Song} "Song reverted"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID or revision"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required or role is not allowed to revert songs"
+// @Failure      404  {object}  utils.Response{message=string} "Revision not found"
+// @Failure      409  {object}  utils.Response{message=string} "Song with the same name and artist already exists"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/revert [post]
+func (sc *SongController) RevertSong(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), sc.Timeout)
+	defer cancel()
+	// Extract song id and revision from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	revision, err := strconv.Atoi(c.QueryParam("revision"))
+	if err != nil || revision < 1 {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid revision %s", c.QueryParam("revision"))})
+	}
+	if err := sc.PolicyService.Authorize(ctx, services.ActionSongRevert); err != nil {
+		return c.JSON(http.StatusForbidden, utils.Response{Message: err.Error()})
+	}
+	song, err := sc.SongService.RevertSong(ctx, id, revision)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.JSON(
+				http.StatusConflict,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Song reverted", Data: song})
+}
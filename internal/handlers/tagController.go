@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type TagController struct {
+	TagService services.ITagService
+	Timeout    time.Duration
+}
+
+func NewTagController(tagS services.ITagService, cfg *config.Config) *TagController {
+	timeout := time.Duration(cfg.Server.Timeout) * time.Second
+
+	return &TagController{tagS, timeout}
+}
+
+// @Summary      Get tags
+// @Description  Retrieve tags used in the library with the number of songs labeled with them, most used first. Songs in trash are not counted.
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  utils.Response{message=string, data=[]models.TagCount} "Tags received"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Router       /tags [get]
+func (tc *TagController) GetTags(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), tc.Timeout)
+	defer cancel()
+	tags, err := tc.TagService.GetTags(ctx)
+	if err != nil {
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Tags received", Data: tags})
+}
+
+// @Summary      Add tags to a song
+// @Description  Label the song with free-form tags, tags are trimmed and lowercased. Tags already on the song are skipped.
+// @Description  Tags are not recorded in song history.
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        id    path     int  true  "Song ID"
+// @Param        tags  body     utils.TagsRequest  true  "Tags to add"
+// @Success      200  {object}  utils.Response{message=string, data=models.Song} "Tags added"
+// @Failure      400  {object}  utils.Response{message=string, data=[]string} "Invalid song ID or request"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song not found"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/tags [post]
+func (tc *TagController) AddSongTags(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), tc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	// Extract tags from request
+	tReq := new(utils.TagsRequest)
+	if err := c.Bind(tReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.Response{Message: err.Error()})
+	}
+	if err := validator.New().Struct(tReq); err != nil {
+		var errors []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errors = append(errors, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+		}
+
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: "invalid request", Data: errors})
+	}
+	song, err := tc.TagService.AddSongTags(ctx, id, tReq.Tags)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(
+		http.StatusOK,
+		utils.Response{Message: "Tags added", Data: song})
+}
+
+// @Summary      Remove a tag from a song
+// @Description  Remove the tag from the song, tags no longer used by any song are deleted
+// @Tags         Tags
+// @Accept       json
+// @Produce      json
+// @Param        id   path     int     true  "Song ID"
+// @Param        tag  path     string  true  "Tag"
+// @Success      200  {object}  utils.Response{message=string} "Tag removed"
+// @Failure      400  {object}  utils.Response{message=string} "Invalid song ID"
+// @Failure      401  {object}  utils.Response{message=string} "Authentication required"
+// @Failure      403  {object}  utils.Response{message=string} "Write scope required"
+// @Failure      404  {object}  utils.Response{message=string} "Song doesn't have the tag"
+// @Failure      500  {object}  utils.Response{message=string} "Internal server error"
+// @Security     BearerAuth
+// @Security     APIKeyAuth
+// @Router       /songs/{id}/tags/{tag} [delete]
+func (tc *TagController) RemoveSongTag(c echo.Context) error {
+	// New context with timeout
+	ctx, cancel := context.WithTimeout(c.Request().Context(), tc.Timeout)
+	defer cancel()
+	// Extract song id from request
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.Response{Message: fmt.Sprintf("Invalid song id %s", c.Param("id"))})
+	}
+	if err := tc.TagService.RemoveSongTag(ctx, id, c.Param("tag")); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.Response{Message: err.Error()})
+		}
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.Response{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, utils.Response{Message: "Tag removed"})
+}
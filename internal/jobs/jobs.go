@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Every runs fn once per interval until ctx is cancelled, the job doesn't run if interval isn't positive
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Error().Msgf("Job %s not started, interval should be positive, got %s", name, interval)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info().Msgf("Job %s started, interval %s", name, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msgf("Job %s stopped", name)
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				log.Error().Err(err).Msgf("Job %s failed", name)
+			}
+		}
+	}
+}
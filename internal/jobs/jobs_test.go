@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEveryInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Every(context.Background(), "test", interval, func(ctx context.Context) error {
+				t.Errorf("Expected job with interval %s not to run", interval)
+				return nil
+			})
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected job with interval %s to return", interval)
+		}
+	}
+}
+
+func TestEveryStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Every(ctx, "test", time.Millisecond, func(ctx context.Context) error {
+			select {
+			case runs <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+	}()
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected job to run")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected job to stop when context is cancelled")
+	}
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"music-lib/internal/config"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Formats of log output
+const (
+	FormatConsole = "console" // Human readable lines
+	FormatJSON    = "json"    // JSON object per line
+)
+
+// New builds the logger described by the logging section of config and sets the level with SetLevel.
+// Format is console if it is not set
+func New(cfg *config.Config) (zerolog.Logger, error) {
+	if err := SetLevel(cfg); err != nil {
+		return zerolog.Nop(), err
+	}
+	out, err := output(cfg, os.Stderr)
+	if err != nil {
+		return zerolog.Nop(), err
+	}
+	c := zerolog.New(out).With().Timestamp()
+	if cfg.Logging.Caller {
+		c = c.Caller()
+	}
+	logger := c.Logger()
+	if n := cfg.Logging.Sample; n > 1 {
+		// Messages of other levels are always written
+		logger = logger.Sample(zerolog.LevelSampler{
+			TraceSampler: &zerolog.BasicSampler{N: n},
+			DebugSampler: &zerolog.BasicSampler{N: n},
+		})
+	}
+	return logger, nil
+}
+
+// output returns the writer of events in the format of config to w, events are redacted before they are formatted
+func output(cfg *config.Config, w io.Writer) (io.Writer, error) {
+	var out io.Writer
+	switch cfg.Logging.Format {
+	case "", FormatConsole:
+		out = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	case FormatJSON:
+		out = w
+	default:
+		return nil, fmt.Errorf("logging: unknown format %s", cfg.Logging.Format)
+	}
+	return newRedactWriter(out, cfg.Logging.Redact), nil
+}
+
+// SetLevel sets the minimum level of messages written by all loggers, info if it is not set.
+// The level can be changed while the server is running
+func SetLevel(cfg *config.Config) error {
+	level := zerolog.InfoLevel
+	if cfg.Logging.Level != "" {
+		l, err := zerolog.ParseLevel(cfg.Logging.Level)
+		if err != nil {
+			return fmt.Errorf("logging: %v", err)
+		}
+		level = l
+	}
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
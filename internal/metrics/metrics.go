@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "musiclib"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_query_duration_seconds",
+		Help:      "Duration of repository methods by repository and method, including every query they run.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms to ~4s
+	}, []string{"repository", "method"})
+
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "music_info_requests_total",
+		Help:      "Number of requests to the music info API by outcome.",
+	}, []string{"outcome"})
+
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "music_info_request_duration_seconds",
+		Help:      "Duration of requests to the music info API by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+)
+
+// Outcomes of requests to the music info API
+const (
+	OutcomeSuccess  = "success"
+	OutcomeNotFound = "not_found"
+	OutcomeStatus   = "unexpected_status" // Any other status
+	OutcomeInvalid  = "invalid_response"
+	OutcomeError    = "error" // Request was not sent or response was not received
+)
+
+// ObserveQuery records duration of the repository method started at start
+func ObserveQuery(repository, method string, start time.Time) {
+	QueryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveUpstream records the outcome and duration of the music info request started at start
+func ObserveUpstream(outcome string, start time.Time) {
+	UpstreamRequests.WithLabelValues(outcome).Inc()
+	UpstreamDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
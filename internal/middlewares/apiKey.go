@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"music-lib/internal/db/models"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// apiKeySubjectPrefix starts subjects of API keys, tokens can't have subjects with it
+const apiKeySubjectPrefix = "apikey:"
+
+// APIKey authenticates requests with X-API-Key header as "apikey:<name>" with scopes of the key.
+// Role of the key follows its scopes: admin for admin scope, editor for write and viewer otherwise.
+// Keys issued with a library are bound to it.
+// Requests without the header are passed through unchanged, invalid keys are rejected
+// as well as requests with both an API key and an authorization header
+func APIKey(s services.IAPIKeyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(APIKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if c.Request().Header.Get(echo.HeaderAuthorization) != "" {
+				return c.JSON(
+					http.StatusBadRequest,
+					utils.Response{Message: "send either an authorization header or an API key, not both"})
+			}
+			apiKey, err := s.Authenticate(c.Request().Context(), key)
+			if err != nil {
+				if strings.Contains(err.Error(), "unauthorized") {
+					return unauthorized(c, err.Error())
+				}
+				return c.JSON(
+					http.StatusInternalServerError,
+					utils.Response{Message: err.Error()})
+			}
+			role := utils.RoleViewer
+			if apiKey.HasScope(models.ScopeAdmin) {
+				role = utils.RoleAdmin
+			} else if apiKey.HasScope(models.ScopeWrite) {
+				role = utils.RoleEditor
+			}
+			library := ""
+			if apiKey.Library != nil {
+				library = *apiKey.Library
+			}
+			setPrincipal(c, apiKeySubjectPrefix+apiKey.Name, apiKey.Scopes, role, library)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// staticAPIKeyService authenticates the single key "valid-key", other methods aren't implemented
+type staticAPIKeyService struct {
+	services.IAPIKeyService
+}
+
+func (s staticAPIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if key != "valid-key" {
+		return nil, fmt.Errorf("unauthorized error: invalid API key")
+	}
+	return &models.APIKey{Name: "import", Scopes: []string{models.ScopeRead, models.ScopeWrite}}, nil
+}
+
+func TestAPIKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiKey        string
+		authorization string
+		code          int
+		subject       string
+	}{
+		{"no key", "", "", http.StatusOK, ""},
+		{"valid key", "valid-key", "", http.StatusOK, "apikey:import"},
+		{"invalid key", "other-key", "", http.StatusUnauthorized, ""},
+		{"key and bearer token", "valid-key", "Bearer token", http.StatusBadRequest, ""},
+		{"key and basic auth", "valid-key", "Basic YWxpY2U6c2VjcmV0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			err := APIKey(staticAPIKeyService{})(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if rec.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d", tt.code, rec.Code)
+			}
+			if subject, _ := c.Get(SubjectKey).(string); subject != tt.subject {
+				t.Fatalf("Expected subject %q, got %q", tt.subject, subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"music-lib/internal/utils"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Echo context keys of the authenticated principal
+const (
+	SubjectKey      = "subject"
+	ScopesKey       = "scopes"
+	BoundLibraryKey = "bound-library" // Set if the principal can access only one library
+)
+
+// setPrincipal stores the authenticated subject, its scopes and the library it is bound to if any,
+// the subject and its role become the actor and the role of the request
+func setPrincipal(c echo.Context, subject string, scopes []string, role, library string) {
+	c.Set(SubjectKey, subject)
+	c.Set(ScopesKey, scopes)
+	if library != "" {
+		c.Set(BoundLibraryKey, library)
+	}
+	ctx := utils.WithActor(c.Request().Context(), subject)
+	ctx = utils.WithRole(ctx, role)
+	c.SetRequest(c.Request().WithContext(ctx))
+}
+
+// RequireScope rejects anonymous requests with 401 and requests without the scope with 403
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := c.Get(SubjectKey).(string); !ok {
+				return unauthorized(c, "authentication required")
+			}
+			scopes, _ := c.Get(ScopesKey).([]string)
+			if !slices.Contains(scopes, scope) {
+				return c.JSON(
+					http.StatusForbidden,
+					utils.Response{Message: "scope " + scope + " is required"})
+			}
+			return next(c)
+		}
+	}
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return c.JSON(http.StatusUnauthorized, utils.Response{Message: message})
+}
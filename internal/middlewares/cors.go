@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"music-lib/internal/config"
+	"music-lib/internal/utils"
+	"slices"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// CORS allows cross-origin requests from browsers on configured origins,
+// origins can be changed with Configure while the server is running
+type CORS struct {
+	origins atomic.Pointer[[]string]
+}
+
+func NewCORS(cfg *config.Config) *CORS {
+	c := &CORS{}
+	c.Configure(cfg)
+	return c
+}
+
+// Configure applies origins of the CORS section of cfg to subsequent requests
+func (m *CORS) Configure(cfg *config.Config) {
+	origins := slices.Clone(cfg.CORS.AllowOrigins)
+	m.origins.Store(&origins)
+}
+
+func (m *CORS) Middleware() echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: m.allowed,
+		ExposeHeaders: []string{
+			utils.HeaderRequestID, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+		},
+	})
+}
+
+func (m *CORS) allowed(origin string) (bool, error) {
+	origins := *m.origins.Load()
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin), nil
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// bodyRecorder copies everything written to the response
+type bodyRecorder struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Idempotency replays the stored response for requests repeated with the same Idempotency-Key header.
+// Requests without the header are passed through unchanged
+func Idempotency(s services.IIdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(
+					http.StatusBadRequest,
+					utils.Response{Message: "Idempotency-Key is too long"})
+			}
+			// Read body to fingerprint the request, then restore it for the handler
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(
+					http.StatusBadRequest,
+					utils.Response{Message: err.Error()})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			// Keys are kept per actor and library, so keys of other users and libraries don't collide
+			fingerprint := fingerprintRequest(c.Request().Method, c.Request().URL.Path, body)
+			record, err := s.Begin(ctx, key, fingerprint)
+			if err != nil {
+				if strings.Contains(err.Error(), "mismatch") {
+					return c.JSON(
+						http.StatusUnprocessableEntity,
+						utils.Response{Message: err.Error()})
+				}
+				if strings.Contains(err.Error(), "in progress") {
+					return c.JSON(
+						http.StatusConflict,
+						utils.Response{Message: err.Error()})
+				}
+				return c.JSON(
+					http.StatusInternalServerError,
+					utils.Response{Message: err.Error()})
+			}
+			if record.StatusCode != nil {
+				log.Ctx(ctx).Debug().Msgf("Replaying response for idempotency key %s", key)
+				c.Response().Header().Set(IdempotentReplayedHeader, "true")
+				return c.JSONBlob(*record.StatusCode, record.ResponseBody)
+			}
+			// Capture response of the handler
+			rec := &bodyRecorder{ResponseWriter: c.Response().Writer, body: new(bytes.Buffer)}
+			c.Response().Writer = rec
+			err = next(c)
+			c.Response().Writer = rec.ResponseWriter
+
+			// The request context may be canceled by now, the key is released or completed anyway
+			storeCtx := context.WithoutCancel(ctx)
+			status := c.Response().Status
+			if err != nil || !replayable(status) {
+				if abortErr := s.Abort(storeCtx, record); abortErr != nil {
+					log.Ctx(ctx).Warn().Err(abortErr).Msgf("Idempotency key %s stays reserved until its lease expires", key)
+				}
+				return err
+			}
+			if err := s.Complete(storeCtx, record, status, rec.body.Bytes()); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msgf("Response for idempotency key %s is not stored, retries are rejected until its lease expires", key)
+			}
+			return nil
+		}
+	}
+}
+
+// replayable reports whether the response with the status is stored for replay.
+// Server errors and responses that depend on credentials, limits or other requests in progress
+// aren't stored, so the client can retry
+func replayable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+// fingerprintRequest hashes method, path and body of the request
+func fingerprintRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"music-lib/internal/db/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// recordingIdempotencyService records whether the key was completed or released and with what context
+type recordingIdempotencyService struct {
+	completed, aborted bool
+	ctxErr             error
+}
+
+func (s *recordingIdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyKey, error) {
+	return &models.IdempotencyKey{Key: key, Token: "token", Fingerprint: fingerprint}, nil
+}
+
+func (s *recordingIdempotencyService) Complete(
+	ctx context.Context, reservation *models.IdempotencyKey, status int, body []byte,
+) error {
+	s.completed, s.ctxErr = true, ctx.Err()
+	return nil
+}
+
+func (s *recordingIdempotencyService) Abort(ctx context.Context, reservation *models.IdempotencyKey) error {
+	s.aborted, s.ctxErr = true, ctx.Err()
+	return errors.New("connection refused")
+}
+
+func (s *recordingIdempotencyService) PurgeExpired(ctx context.Context) error {
+	return nil
+}
+
+// serveIdempotent runs a request with Idempotency-Key whose context is canceled by the handler
+func serveIdempotent(s *recordingIdempotencyService, status int) {
+	e := echo.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	c := e.NewContext(req, httptest.NewRecorder())
+	handler := Idempotency(s)(func(c echo.Context) error {
+		// Client went away while the request was processed
+		cancel()
+		return c.NoContent(status)
+	})
+	handler(c)
+}
+
+func TestIdempotencyCanceledRequest(t *testing.T) {
+	s := &recordingIdempotencyService{}
+	serveIdempotent(s, http.StatusCreated)
+	if !s.completed || s.ctxErr != nil {
+		t.Fatalf("Expected response to be stored with live context, completed %t, context error %v", s.completed, s.ctxErr)
+	}
+
+	s = &recordingIdempotencyService{}
+	serveIdempotent(s, http.StatusInternalServerError)
+	if !s.aborted || s.ctxErr != nil {
+		t.Fatalf("Expected key to be released with live context, aborted %t, context error %v", s.aborted, s.ctxErr)
+	}
+}
+
+func TestIdempotencyStoredStatuses(t *testing.T) {
+	tests := []struct {
+		status int
+		stored bool
+	}{
+		{http.StatusCreated, true},
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusUnprocessableEntity, true},
+		{http.StatusUnauthorized, false},
+		{http.StatusForbidden, false},
+		{http.StatusConflict, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		s := &recordingIdempotencyService{}
+		serveIdempotent(s, tt.status)
+		if s.completed != tt.stored || s.aborted == tt.stored {
+			t.Fatalf("Status %d: expected stored %t, completed %t, aborted %t", tt.status, tt.stored, s.completed, s.aborted)
+		}
+	}
+}
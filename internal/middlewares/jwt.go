@@ -0,0 +1,205 @@
+package middlewares
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/services"
+	"music-lib/internal/utils"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+// JWTAuth verifies bearer tokens signed with HS256 using a shared secret
+// or with RS256 using public keys from a JWKS file. Without both bearer tokens are rejected
+type JWTAuth struct {
+	secret      []byte
+	keys        map[string]*rsa.PublicKey // by key id
+	issuer      string
+	audience    string
+	defaultRole string
+}
+
+func NewJWTAuth(cfg *config.Config) (*JWTAuth, error) {
+	a := &JWTAuth{
+		secret:      []byte(cfg.Auth.HMACSecret),
+		issuer:      cfg.Auth.Issuer,
+		audience:    cfg.Auth.Audience,
+		defaultRole: cfg.RBAC.DefaultRole,
+	}
+	if a.defaultRole == "" {
+		a.defaultRole = utils.RoleViewer
+	}
+	if !services.IsRole(a.defaultRole) {
+		return nil, fmt.Errorf("rbac: unknown default role %s", a.defaultRole)
+	}
+	if cfg.Auth.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.Auth.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+// Enabled reports whether there are keys to verify tokens with
+func (a *JWTAuth) Enabled() bool {
+	return len(a.secret) > 0 || len(a.keys) > 0
+}
+
+// Authenticate verifies the bearer token when the request has one and puts its subject in the request context
+// as the actor. Tokens should expire and can't have subjects of API keys or anonymous requests. Scopes are read from space separated scope claim, read and write are granted if it is missing.
+// Role is read from role claim, the configured default role is used if it is missing.
+// Tokens with library claim are bound to the library.
+// Requests without a token are passed through as anonymous, invalid tokens are rejected
+func (a *JWTAuth) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if header == "" {
+			return next(c)
+		}
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return unauthorized(c, "authorization header should be a bearer token")
+		}
+		if !a.Enabled() {
+			return unauthorized(c, "bearer tokens are not accepted, use an API key")
+		}
+		p, err := a.verify(tokenString)
+		if err != nil {
+			return unauthorized(c, "invalid token: "+err.Error())
+		}
+		setPrincipal(c, p.subject, p.scopes, p.role, p.library)
+		return next(c)
+	}
+}
+
+// tokenPrincipal is the principal described by claims of a verified token
+type tokenPrincipal struct {
+	subject string
+	scopes  []string
+	role    string
+	library string
+}
+
+// verify checks signature and claims of the token and returns its principal
+func (a *JWTAuth) verify(tokenString string) (*tokenPrincipal, error) {
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(tokenString, claims, a.key); err != nil {
+		return nil, err
+	}
+	// Expired tokens are rejected by the parser, tokens that never expire are not accepted either
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("expiration time is missing")
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer")
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	p := &tokenPrincipal{
+		scopes: []string{models.ScopeRead, models.ScopeWrite},
+		role:   a.defaultRole,
+	}
+	p.subject, _ = claims["sub"].(string)
+	if p.subject == "" {
+		return nil, fmt.Errorf("subject is missing")
+	}
+	// Subjects of API keys and anonymous requests can't be taken by tokens
+	if p.subject == utils.AnonymousActor || strings.HasPrefix(p.subject, apiKeySubjectPrefix) {
+		return nil, fmt.Errorf("subject %s is reserved", p.subject)
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.scopes = strings.Fields(scope)
+	}
+	if role, ok := claims["role"].(string); ok {
+		if !services.IsRole(role) {
+			return nil, fmt.Errorf("unknown role %s", role)
+		}
+		p.role = role
+	}
+	if library, ok := claims["library"].(string); ok {
+		if !utils.IsLibraryName(library) {
+			return nil, fmt.Errorf("invalid library %s", library)
+		}
+		p.library = library
+	}
+	return p, nil
+}
+
+// key returns the key to verify the token with, depending on its algorithm
+func (a *JWTAuth) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if len(a.secret) == 0 {
+			return nil, fmt.Errorf("HS256 tokens are not accepted")
+		}
+		return a.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.keys[kid]; ok {
+			return key, nil
+		}
+		if kid == "" && len(a.keys) == 1 {
+			// Key id can be omitted when there is a single key
+			for _, key := range a.keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// loadJWKS reads RSA public keys from a JWK Set file (RFC 7517), other key types are skipped
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.Unmarshal(file, &set); err != nil {
+		return nil, fmt.Errorf("auth: invalid jwks file: %v", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid modulus of key %q: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid exponent of key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("auth: no RSA keys in jwks file %s", path)
+	}
+	return keys, nil
+}
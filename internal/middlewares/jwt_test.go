@@ -0,0 +1,298 @@
+package middlewares
+
+import (
+	"crypto/rsa"
+	"music-lib/internal/config"
+	"music-lib/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const testSecret = "test-secret"
+
+func readPrivateKey(t *testing.T, path string) *rsa.PrivateKey {
+	t.Helper()
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading key: %v", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		t.Fatalf("Error parsing key: %v", err)
+	}
+	return key
+}
+
+func newTestJWTAuth(t *testing.T, change func(cfg *config.Config)) *JWTAuth {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Auth.HMACSecret = testSecret
+	cfg.Auth.JWKSFile = "testdata/jwks.json"
+	if change != nil {
+		change(cfg)
+	}
+	a, err := NewJWTAuth(cfg)
+	if err != nil {
+		t.Fatalf("Error creating JWT auth: %v", err)
+	}
+	return a
+}
+
+// signHS256 signs claims with the test secret, sub and exp are added unless set
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	return sign(t, jwt.NewWithClaims(jwt.SigningMethodHS256, withDefaults(claims)), []byte(testSecret))
+}
+
+// signRS256 signs claims with the key from testdata, kid header is set if not empty
+func signRS256(t *testing.T, keyPath, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, withDefaults(claims))
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return sign(t, token, readPrivateKey(t, keyPath))
+}
+
+// withDefaults adds sub and exp unless set, claims set to nil are left out
+func withDefaults(claims jwt.MapClaims) jwt.MapClaims {
+	if _, ok := claims["sub"]; !ok {
+		claims["sub"] = "alice"
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(claims, name)
+		}
+	}
+	return claims
+}
+
+func sign(t *testing.T, token *jwt.Token, key interface{}) string {
+	t.Helper()
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Error signing token: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerifyKeys(t *testing.T) {
+	a := newTestJWTAuth(t, nil)
+	tests := []struct {
+		name    string
+		token   string
+		problem string // empty if the token is valid
+	}{
+		{"HS256", signHS256(t, jwt.MapClaims{}), ""},
+		{"RS256 first key", signRS256(t, "testdata/rsa-1.pem", "key-1", jwt.MapClaims{}), ""},
+		{"RS256 second key", signRS256(t, "testdata/rsa-2.pem", "key-2", jwt.MapClaims{}), ""},
+		{"RS256 wrong key for kid", signRS256(t, "testdata/rsa-2.pem", "key-1", jwt.MapClaims{}), "verification error"},
+		{"RS256 unknown kid", signRS256(t, "testdata/rsa-1.pem", "key-3", jwt.MapClaims{}), "unknown key id"},
+		{"RS256 encryption key", signRS256(t, "testdata/rsa-2.pem", "key-enc", jwt.MapClaims{}), "unknown key id"},
+		{"RS256 without kid and many keys", signRS256(t, "testdata/rsa-1.pem", "", jwt.MapClaims{}), "unknown key id"},
+		{"RS256 unknown key", signRS256(t, "testdata/rsa-other.pem", "key-1", jwt.MapClaims{}), "verification error"},
+		{"HS256 wrong secret", sign(t, jwt.NewWithClaims(jwt.SigningMethodHS256, withDefaults(jwt.MapClaims{})), []byte("other")), "signature is invalid"},
+		{"HS512", sign(t, jwt.NewWithClaims(jwt.SigningMethodHS512, withDefaults(jwt.MapClaims{})), []byte(testSecret)), "signing method"},
+		{"none", sign(t, jwt.NewWithClaims(jwt.SigningMethodNone, withDefaults(jwt.MapClaims{})), jwt.UnsafeAllowNoneSignatureType), "signing method"},
+		{"expired", signHS256(t, jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), "expired"},
+		{"never expires", signHS256(t, jwt.MapClaims{"exp": nil}), "expiration time is missing"},
+		{"RS256 never expires", signRS256(t, "testdata/rsa-1.pem", "key-1", jwt.MapClaims{"exp": nil}), "expiration time is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.verify(tt.token)
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("Expected token to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("Expected %q error, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifySingleKey(t *testing.T) {
+	// Key id can be omitted with a single key, HS256 tokens are rejected without a secret
+	a := newTestJWTAuth(t, func(cfg *config.Config) {
+		cfg.Auth.HMACSecret = ""
+		cfg.Auth.JWKSFile = "testdata/jwks-single.json"
+	})
+	if _, err := a.verify(signRS256(t, "testdata/rsa-1.pem", "", jwt.MapClaims{})); err != nil {
+		t.Fatalf("Expected token without kid to be valid, got %v", err)
+	}
+	if _, err := a.verify(signHS256(t, jwt.MapClaims{})); err == nil || !strings.Contains(err.Error(), "not accepted") {
+		t.Fatalf("Expected HS256 token to be rejected, got %v", err)
+	}
+}
+
+func TestJWTVerifyClaims(t *testing.T) {
+	a := newTestJWTAuth(t, func(cfg *config.Config) {
+		cfg.Auth.Issuer = "https://auth.example.com"
+		cfg.Auth.Audience = "music-lib"
+	})
+	// valid adds expected issuer and audience unless set
+	valid := func(claims jwt.MapClaims) jwt.MapClaims {
+		if _, ok := claims["iss"]; !ok {
+			claims["iss"] = "https://auth.example.com"
+		}
+		if _, ok := claims["aud"]; !ok {
+			claims["aud"] = "music-lib"
+		}
+		return claims
+	}
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		problem string
+	}{
+		{"valid", valid(jwt.MapClaims{}), ""},
+		{"audience list", valid(jwt.MapClaims{"aud": []string{"other", "music-lib"}}), ""},
+		{"wrong issuer", valid(jwt.MapClaims{"iss": "https://evil.example.com"}), "unexpected issuer"},
+		{"missing issuer", jwt.MapClaims{"aud": "music-lib"}, "unexpected issuer"},
+		{"wrong audience", valid(jwt.MapClaims{"aud": "other"}), "unexpected audience"},
+		{"missing audience", jwt.MapClaims{"iss": "https://auth.example.com"}, "unexpected audience"},
+		{"missing subject", valid(jwt.MapClaims{"sub": ""}), "subject is missing"},
+		{"subject not a string", valid(jwt.MapClaims{"sub": 42}), "subject is missing"},
+		{"subject of api key", valid(jwt.MapClaims{"sub": "apikey:import"}), "reserved"},
+		{"anonymous subject", valid(jwt.MapClaims{"sub": utils.AnonymousActor}), "reserved"},
+		{"unknown role", valid(jwt.MapClaims{"role": "owner"}), "unknown role"},
+		{"invalid library", valid(jwt.MapClaims{"library": "Team A"}), "invalid library"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.verify(signHS256(t, tt.claims))
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("Expected token to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("Expected %q error, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyPrincipal(t *testing.T) {
+	a := newTestJWTAuth(t, func(cfg *config.Config) { cfg.RBAC.DefaultRole = utils.RoleEditor })
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		scopes  []string
+		role    string
+		library string
+	}{
+		{"defaults", jwt.MapClaims{}, []string{"read", "write"}, utils.RoleEditor, ""},
+		{"scope claim", jwt.MapClaims{"scope": "read  admin"}, []string{"read", "admin"}, utils.RoleEditor, ""},
+		{"empty scope claim", jwt.MapClaims{"scope": ""}, []string{}, utils.RoleEditor, ""},
+		{"role claim", jwt.MapClaims{"role": utils.RoleModerator}, []string{"read", "write"}, utils.RoleModerator, ""},
+		{"library claim", jwt.MapClaims{"library": "team-a"}, []string{"read", "write"}, utils.RoleEditor, "team-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.verify(signHS256(t, tt.claims))
+			if err != nil {
+				t.Fatalf("Error verifying token: %v", err)
+			}
+			if p.subject != "alice" || !slices.Equal(p.scopes, tt.scopes) || p.role != tt.role || p.library != tt.library {
+				t.Fatalf("Unexpected principal %+v", p)
+			}
+		})
+	}
+}
+
+func TestNewJWTAuthInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(cfg *config.Config)
+		problem string
+	}{
+		{"missing jwks file", func(cfg *config.Config) { cfg.Auth.JWKSFile = "testdata/missing.json" }, "no such file"},
+		{"jwks without RSA keys", func(cfg *config.Config) { cfg.Auth.JWKSFile = "testdata/rsa-1.pem" }, "invalid jwks file"},
+		{"unknown default role", func(cfg *config.Config) { cfg.RBAC.DefaultRole = "owner" }, "unknown default role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Auth.HMACSecret = testSecret
+			cfg.Auth.JWKSFile = "testdata/jwks.json"
+			tt.change(cfg)
+			if _, err := NewJWTAuth(cfg); err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("Expected %q error, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+// authenticate runs a request with the authorization header through a, it is not set if empty
+func authenticate(t *testing.T, a *JWTAuth, authorization string) (int, echo.Context) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+	if authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	err := a.Authenticate(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return rec.Code, c
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	a := newTestJWTAuth(t, nil)
+	serve := func(authorization string) (int, echo.Context) {
+		return authenticate(t, a, authorization)
+	}
+
+	code, c := serve("")
+	if _, ok := c.Get(SubjectKey).(string); code != http.StatusOK || ok {
+		t.Fatalf("Expected anonymous request to pass without subject, got %d", code)
+	}
+	if code, _ := serve("Basic YWxpY2U6c2VjcmV0"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected non bearer authorization to be rejected, got %d", code)
+	}
+	if code, _ := serve("Bearer invalid"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected invalid token to be rejected, got %d", code)
+	}
+	code, c = serve("Bearer " + signHS256(t, jwt.MapClaims{"library": "team-a", "role": utils.RoleModerator}))
+	if code != http.StatusOK {
+		t.Fatalf("Expected valid token to pass, got %d", code)
+	}
+	ctx := c.Request().Context()
+	if c.Get(SubjectKey) != "alice" || c.Get(BoundLibraryKey) != "team-a" ||
+		utils.ActorFromContext(ctx) != "alice" || utils.RoleFromContext(ctx) != utils.RoleModerator {
+		t.Fatalf("Expected principal of the token in the request")
+	}
+}
+
+func TestJWTAuthenticateDisabled(t *testing.T) {
+	a := newTestJWTAuth(t, func(cfg *config.Config) { cfg.Auth.HMACSecret, cfg.Auth.JWKSFile = "", "" })
+	if a.Enabled() {
+		t.Fatalf("Expected JWT authentication to be disabled without keys")
+	}
+	if code, _ := authenticate(t, a, ""); code != http.StatusOK {
+		t.Fatalf("Expected anonymous request to pass, got %d", code)
+	}
+	if code, _ := authenticate(t, a, "Bearer "+signHS256(t, jwt.MapClaims{})); code != http.StatusUnauthorized {
+		t.Fatalf("Expected bearer token to be rejected, got %d", code)
+	}
+}
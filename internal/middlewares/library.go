@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"music-lib/internal/config"
+	"music-lib/internal/utils"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Library puts the library of the request in the request context. It is named by the configured header
+// or by the subdomain of the configured domain, the default library is used otherwise.
+// Principals bound to a library work with it when the request doesn't name one and are rejected
+// with 403 when it names another library. Runs after authentication middlewares
+func Library(cfg *config.Config) echo.MiddlewareFunc {
+	header := cfg.Library.Header
+	domain := strings.ToLower(strings.TrimPrefix(cfg.Library.Domain, "."))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			library := ""
+			if header != "" {
+				library = c.Request().Header.Get(header)
+			}
+			if library == "" && domain != "" {
+				library = subdomain(c.Request().Host, domain)
+			}
+			if library != "" && !utils.IsLibraryName(library) {
+				return c.JSON(
+					http.StatusBadRequest,
+					utils.Response{Message: "invalid library " + library})
+			}
+			if bound, ok := c.Get(BoundLibraryKey).(string); ok {
+				if library != "" && library != bound {
+					return c.JSON(
+						http.StatusForbidden,
+						utils.Response{Message: "library " + library + " is not accessible with these credentials"})
+				}
+				library = bound
+			}
+			if library == "" {
+				library = utils.DefaultLibrary
+			}
+			ctx := utils.WithLibrary(c.Request().Context(), library)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// subdomain returns the label of host before domain, empty if host is not a direct subdomain of domain
+func subdomain(host, domain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+domain)
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
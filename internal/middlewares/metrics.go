@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"music-lib/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Metrics counts requests and measures their duration by route, requests matching no route are counted as unmatched.
+// Errors of handlers are returned to middlewares before it after their response is written
+func Metrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil && !c.Response().Committed {
+			// Let echo write the error response, so the status is known
+			c.Error(err)
+		}
+		route := c.Path()
+		if route == "" || route == "/*" {
+			route = "unmatched"
+		}
+		method := c.Request().Method
+		status := strconv.Itoa(c.Response().Status)
+		metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
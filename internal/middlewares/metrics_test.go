@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"errors"
+	"music-lib/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	e := echo.New()
+	var returned []error
+	// Middleware before Metrics sees errors of handlers
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			returned = append(returned, err)
+			return err
+		}
+	})
+	e.Use(Metrics)
+	e.GET("/songs/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound, "song not found")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/committed", func(c echo.Context) error {
+		c.NoContent(http.StatusAccepted)
+		return errors.New("failed after the response was written")
+	})
+
+	tests := []struct {
+		path   string
+		route  string
+		status int
+		err    bool
+	}{
+		{"/songs/1", "/songs/:id", http.StatusOK, false},
+		{"/songs/0", "/songs/:id", http.StatusNotFound, true},
+		{"/committed", "/committed", http.StatusAccepted, true},
+		{"/missing", "unmatched", http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		status := http.StatusText(tt.status)
+		counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, tt.route, strconv.Itoa(tt.status))
+		before := testutil.ToFloat64(counter)
+		returned = nil
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected status %s, got %d", tt.path, status, rec.Code)
+		}
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Fatalf("%s: expected request counted with route %s and status %d, counted %v", tt.path, tt.route, tt.status, got)
+		}
+		if len(returned) != 1 || (returned[0] != nil) != tt.err {
+			t.Fatalf("%s: expected error returned %t, got %v", tt.path, tt.err, returned)
+		}
+	}
+}
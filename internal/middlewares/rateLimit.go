@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"music-lib/internal/config"
+	"music-lib/internal/utils"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+// Buckets of clients without requests for that long are dropped
+const bucketIdle = 10 * time.Minute
+
+// RateLimiter keeps a token bucket for every client on every route. Clients are identified by the authenticated
+// subject, API keys and tokens have their own buckets, and by IP address otherwise.
+// Limits can be changed with Configure while the server is running, buckets of routes whose limit is changed
+// start full again then
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]config.Limit // by route
+	def     config.Limit
+	buckets map[string]*bucket // by route and client
+	swept   time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	route   string
+	limit   config.Limit // Limit of the route the bucket was made with
+	seen    time.Time
+}
+
+func NewRateLimiter(cfg *config.Config) *RateLimiter {
+	l := &RateLimiter{}
+	l.Configure(cfg)
+	return l
+}
+
+// Configure applies limits of the rate limit section of cfg
+func (l *RateLimiter) Configure(cfg *config.Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.def = cfg.RateLimit.Default
+	l.limits = cfg.RateLimit.Routes
+	if l.buckets == nil {
+		l.buckets = map[string]*bucket{}
+		l.swept = time.Now()
+	}
+	// Clients keep their tokens on routes with the same limit
+	for key, b := range l.buckets {
+		if l.limit(b.route) != b.limit {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limit returns the limit of the route, the default limit if the route has none
+func (l *RateLimiter) limit(route string) config.Limit {
+	if limit, ok := l.limits[route]; ok {
+		return limit
+	}
+	return l.def
+}
+
+// Middleware rejects requests over the limit of the route with 429, responses carry RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers. Runs after authentication middlewares
+func (l *RateLimiter) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		route := c.Request().Method + " " + c.Path()
+		client := "ip:" + c.RealIP()
+		if subject, ok := c.Get(SubjectKey).(string); ok {
+			client = "subject:" + subject
+		}
+		limit, b := l.bucket(route, client)
+		if b == nil {
+			return next(c)
+		}
+
+		now := time.Now()
+		allowed := b.limiter.AllowN(now, 1)
+		tokens := b.limiter.TokensAt(now)
+		header := c.Response().Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+		// Seconds until the bucket is full again
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(float64(limit.Burst)-tokens, limit.Rate)))
+		if !allowed {
+			retry := seconds(1-tokens, limit.Rate)
+			header.Set("Retry-After", strconv.Itoa(retry))
+			return c.JSON(
+				http.StatusTooManyRequests,
+				utils.Response{Message: fmt.Sprintf("rate limit exceeded, retry in %d seconds", retry)})
+		}
+		return next(c)
+	}
+}
+
+// bucket returns the limit of the route and the bucket of the client on it, nil if the route is not limited
+func (l *RateLimiter) bucket(route, client string) (config.Limit, *bucket) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit := l.limit(route)
+	if limit.Rate == 0 {
+		return limit, nil
+	}
+	now := time.Now()
+	if now.Sub(l.swept) > bucketIdle {
+		for key, b := range l.buckets {
+			if now.Sub(b.seen) > bucketIdle {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+	key := route + " " + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst), route: route, limit: limit}
+		l.buckets[key] = b
+	}
+	b.seen = now
+	return limit, b
+}
+
+// IPExtractor finds client IP for c.RealIP, echo trusts X-Forwarded-For and X-Real-IP of any client by default
+// so clients could pick their own rate limit bucket. Forwarding headers are trusted only from server.trusted-proxies
+func IPExtractor(cfg *config.Config) echo.IPExtractor {
+	if len(cfg.Server.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range cfg.Server.TrustedProxies {
+		// Checked in config validation
+		if _, ipRange, err := net.ParseCIDR(cidr); err == nil {
+			options = append(options, echo.TrustIPRange(ipRange))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// seconds returns whole seconds to accumulate the number of tokens at the rate
+func seconds(tokens, perSecond float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / perSecond))
+}
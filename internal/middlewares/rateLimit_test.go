@@ -0,0 +1,193 @@
+package middlewares
+
+import (
+	"music-lib/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func limiterConfig(def config.Limit, routes map[string]config.Limit) *config.Config {
+	cfg := &config.Config{}
+	cfg.RateLimit.Default = def
+	cfg.RateLimit.Routes = routes
+	return cfg
+}
+
+// serve runs a GET /songs request from the address through the limiter, subject is set if not empty
+func serve(e *echo.Echo, l *RateLimiter, remoteAddr, subject string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/songs")
+	if subject != "" {
+		c.Set(SubjectKey, subject)
+	}
+	handler := l.Middleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	if err := handler(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	e := echo.New()
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 0.001, Burst: 2}, nil))
+	for i := 0; i < 2; i++ {
+		if rec := serve(e, l, "192.0.2.1:1000", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := serve(e, l, "192.0.2.1:1000", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected request over the burst to be rejected, got %d", rec.Code)
+	}
+	// Other clients have their own buckets
+	if rec := serve(e, l, "192.0.2.2:1000", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected request of another IP to pass, got %d", rec.Code)
+	}
+	if rec := serve(e, l, "192.0.2.1:1000", "apikey:import", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected request of authenticated subject to pass, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	e := echo.New()
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 1, Burst: 2}, nil))
+	rec := serve(e, l, "192.0.2.1:1000", "", nil)
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Fatalf("Expected RateLimit-Limit 2, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Fatalf("Expected RateLimit-Remaining 1, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Reset"); got != "1" {
+		t.Fatalf("Expected RateLimit-Reset 1, got %q", got)
+	}
+	serve(e, l, "192.0.2.1:1000", "", nil)
+	rec = serve(e, l, "192.0.2.1:1000", "", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected request over the burst to be rejected, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("Expected Retry-After 1, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Fatalf("Expected RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestRateLimiterRoutes(t *testing.T) {
+	e := echo.New()
+	// Route without limit isn't limited even if the default is
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 1, Burst: 1}, map[string]config.Limit{"GET /songs": {}}))
+	for i := 0; i < 3; i++ {
+		rec := serve(e, l, "192.0.2.1:1000", "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("Expected no rate limit headers on unlimited route")
+		}
+	}
+}
+
+func TestRateLimiterConfigure(t *testing.T) {
+	e := echo.New()
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 0.001, Burst: 1}, nil))
+	serve(e, l, "192.0.2.1:1000", "", nil)
+	if rec := serve(e, l, "192.0.2.1:1000", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected request over the burst to be rejected, got %d", rec.Code)
+	}
+	// Buckets start full again with new limits
+	l.Configure(limiterConfig(config.Limit{Rate: 0.001, Burst: 3}, nil))
+	rec := serve(e, l, "192.0.2.1:1000", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected request to pass after reconfiguring, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "3" {
+		t.Fatalf("Expected RateLimit-Limit 3, got %q", got)
+	}
+}
+
+func TestRateLimiterConfigureSameLimit(t *testing.T) {
+	e := echo.New()
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 0.001, Burst: 1}, nil))
+	serve(e, l, "192.0.2.1:1000", "", nil)
+	// Reloading config with the same limit of the route keeps buckets
+	l.Configure(limiterConfig(config.Limit{Rate: 0.001, Burst: 1}, map[string]config.Limit{"POST /songs": {Rate: 1, Burst: 5}}))
+	if rec := serve(e, l, "192.0.2.1:1000", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected bucket to be kept after reload, got %d", rec.Code)
+	}
+	// The route gets its own limit, its buckets start full
+	l.Configure(limiterConfig(config.Limit{Rate: 0.001, Burst: 1}, map[string]config.Limit{"GET /songs": {Rate: 0.001, Burst: 2}}))
+	if rec := serve(e, l, "192.0.2.1:1000", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected bucket to start full with the new limit of the route, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 1, Burst: 1}, nil))
+	l.bucket("GET /songs", "ip:192.0.2.1")
+	l.bucket("GET /songs", "ip:192.0.2.2")
+	// The first client is idle for too long, the second one was just seen
+	l.mu.Lock()
+	l.buckets["GET /songs ip:192.0.2.1"].seen = time.Now().Add(-2 * bucketIdle)
+	l.swept = time.Now().Add(-2 * bucketIdle)
+	l.mu.Unlock()
+	l.bucket("GET /songs", "ip:192.0.2.3")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.buckets["GET /songs ip:192.0.2.1"]; ok {
+		t.Fatalf("Expected idle bucket to be dropped")
+	}
+	if len(l.buckets) != 2 {
+		t.Fatalf("Expected 2 buckets left, got %d", len(l.buckets))
+	}
+}
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{"no proxies ignore header", nil, "192.0.2.1:1000", "192.0.2.1"},
+		{"private network isn't trusted", nil, "10.0.0.1:1000", "10.0.0.1"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.0.0.1:1000", "203.0.113.7"},
+		{"untrusted proxy", []string{"10.0.0.0/8"}, "192.0.2.1:1000", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.TrustedProxies = tt.proxies
+			req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+			req.Header.Set(echo.HeaderXRealIP, "203.0.113.8")
+			if got := IPExtractor(cfg)(req); got != tt.want {
+				t.Fatalf("Expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSpoofedHeader(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = IPExtractor(&config.Config{})
+	l := NewRateLimiter(limiterConfig(config.Limit{Rate: 0.001, Burst: 1}, nil))
+	serve(e, l, "192.0.2.1:1000", "", map[string]string{echo.HeaderXForwardedFor: "203.0.113.1"})
+	rec := serve(e, l, "192.0.2.1:1000", "", map[string]string{echo.HeaderXForwardedFor: "203.0.113.2"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected forged X-Forwarded-For not to give a new bucket, got %d", rec.Code)
+	}
+}
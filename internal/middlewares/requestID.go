@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"music-lib/internal/utils"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Request IDs accepted from clients, others are replaced
+var requestID = regexp.MustCompile(`^[\w.:-]{1,128}$`)
+
+// RequestID takes the ID of the request from X-Request-ID header or generates one and returns it
+// in the same header. The ID is put in the request context along with a logger adding it to every message
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(utils.HeaderRequestID)
+		if !requestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Response().Header().Set(utils.HeaderRequestID, id)
+		ctx := utils.WithRequestID(c.Request().Context(), id)
+		ctx = log.Logger.With().Str("request_id", id).Logger().WithContext(ctx)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+// JSONSerializer adds the request ID to error responses
+type JSONSerializer struct {
+	echo.DefaultJSONSerializer
+}
+
+func (s JSONSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	if r, ok := i.(utils.Response); ok && c.Response().Status >= http.StatusBadRequest {
+		r.RequestID = utils.RequestIDFromContext(c.Request().Context())
+		i = r
+	}
+	return s.DefaultJSONSerializer.Serialize(c, i, indent)
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"music-lib/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// serveWithRequestID runs a request with X-Request-ID header through RequestID, the header is not set if empty.
+// The handler responds with the status and returns the request ID of the context
+func serveWithRequestID(t *testing.T, header string, status int) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	e := echo.New()
+	e.JSONSerializer = JSONSerializer{}
+	req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+	if header != "" {
+		req.Header.Set(utils.HeaderRequestID, header)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var id string
+	err := RequestID(func(c echo.Context) error {
+		id = utils.RequestIDFromContext(c.Request().Context())
+		return c.JSON(status, utils.Response{Message: http.StatusText(status)})
+	})(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return rec, id
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		accepted bool
+	}{
+		{"client id", "client-id_1.2:3", true},
+		{"missing", "", false},
+		{"invalid characters", "id with spaces", false},
+		{"log injection", "id\nlevel=error", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, id := serveWithRequestID(t, tt.header, http.StatusOK)
+			if tt.accepted && id != tt.header {
+				t.Fatalf("Expected request ID %q to be accepted, got %q", tt.header, id)
+			}
+			if !tt.accepted {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Fatalf("Expected request ID to be replaced with a UUID, got %q", id)
+				}
+			}
+			if got := rec.Header().Get(utils.HeaderRequestID); got != id {
+				t.Fatalf("Expected request ID %q in response, got %q", id, got)
+			}
+		})
+	}
+}
+
+func TestJSONSerializerRequestID(t *testing.T) {
+	tests := []struct {
+		status    int
+		requestID string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusCreated, ""},
+		{http.StatusNotFound, "client-id"},
+		{http.StatusInternalServerError, "client-id"},
+	}
+	for _, tt := range tests {
+		rec, _ := serveWithRequestID(t, "client-id", tt.status)
+		body := utils.Response{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+		if body.RequestID != tt.requestID || body.Message != http.StatusText(tt.status) {
+			t.Fatalf("Status %d: expected request ID %q in body, got %s", tt.status, tt.requestID, rec.Body.String())
+		}
+	}
+}
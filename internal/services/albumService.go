@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IAlbumService interface {
+	CreateAlbum(ctx context.Context, album *models.Album) error
+	GetAlbums(ctx context.Context, page, limit int) ([]models.Album, error)
+	GetAlbum(ctx context.Context, id int) (*models.Album, error)
+	UpdateAlbum(ctx context.Context, album *models.Album) error
+	DeleteAlbum(ctx context.Context, id int) error
+	AddTrack(ctx context.Context, track *models.AlbumTrack) error
+	RemoveTrack(ctx context.Context, id, songID int) error
+}
+
+type AlbumService struct {
+	Repo     repository.IAlbumRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewAlbumService(albumRepo repository.IAlbumRepo, songRepo repository.ISongRepo) IAlbumService {
+	return AlbumService{albumRepo, songRepo}
+}
+
+func (s AlbumService) CreateAlbum(ctx context.Context, album *models.Album) error {
+	album.ID = nil
+	if err := s.Repo.Save(ctx, album); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save album")
+		return err
+	}
+	return nil
+}
+
+func (s AlbumService) GetAlbums(ctx context.Context, page, limit int) ([]models.Album, error) {
+	offset := (page - 1) * limit
+	albums, err := s.Repo.GetAll(ctx, offset, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get albums")
+		return nil, err
+	}
+	return albums, nil
+}
+
+// GetAlbum fetches the album with its tracks, songs in trash are left out of the track listing
+func (s AlbumService) GetAlbum(ctx context.Context, id int) (*models.Album, error) {
+	album, err := s.Repo.GetById(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get album with id %d", id)
+		return nil, err
+	}
+	tracks, err := s.Repo.GetTracks(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get tracks of album with id %d", id)
+		return nil, err
+	}
+	songIDs := make([]int, 0, len(tracks))
+	for _, track := range tracks {
+		songIDs = append(songIDs, track.SongID)
+	}
+	songs, err := s.SongRepo.GetByIds(ctx, songIDs)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get songs of album with id %d", id)
+		return nil, err
+	}
+	songsByID := make(map[int]*models.Song, len(songs))
+	for i := range songs {
+		songsByID[*songs[i].ID] = &songs[i]
+	}
+	album.Tracks = []models.AlbumTrack{}
+	for _, track := range tracks {
+		if song, ok := songsByID[track.SongID]; ok {
+			track.Song = song
+			album.Tracks = append(album.Tracks, track)
+		}
+	}
+	return album, nil
+}
+
+func (s AlbumService) UpdateAlbum(ctx context.Context, album *models.Album) error {
+	if err := s.Repo.Save(ctx, album); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update album with id %d", *album.ID)
+		return err
+	}
+	return nil
+}
+
+func (s AlbumService) DeleteAlbum(ctx context.Context, id int) error {
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete album with id %d", id)
+		return err
+	}
+	return nil
+}
+
+func (s AlbumService) AddTrack(ctx context.Context, track *models.AlbumTrack) error {
+	if track.DiscNumber == 0 {
+		track.DiscNumber = 1
+	}
+	if err := s.Repo.AddTrack(ctx, track); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to add song %d to album %d", track.SongID, track.AlbumID)
+		return err
+	}
+	return nil
+}
+
+func (s AlbumService) RemoveTrack(ctx context.Context, id, songID int) error {
+	if err := s.Repo.RemoveTrack(ctx, id, songID); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove song %d from album %d", songID, id)
+		return err
+	}
+	return nil
+}
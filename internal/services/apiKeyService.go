@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// APIKeyPrefix starts every issued key, so leaked keys are easy to search for
+const APIKeyPrefix = "mlk_"
+
+type IAPIKeyService interface {
+	IssueKey(ctx context.Context, name string, scopes []string, library string, ttl time.Duration) (*models.IssuedAPIKey, error)
+	GetKeys(ctx context.Context, library string) ([]models.APIKey, error)
+	RevokeKey(ctx context.Context, id int, library string) error
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type APIKeyService struct {
+	Repo repository.IAPIKeyRepo
+}
+
+func NewAPIKeyService(apiKeyRepo repository.IAPIKeyRepo) IAPIKeyService {
+	return APIKeyService{apiKeyRepo}
+}
+
+// IssueKey generates a random key with the scopes, ttl 0 means the key never expires
+// and empty library means the key can access any library.
+// Only hash of the key is stored, the key is returned once
+func (s APIKeyService) IssueKey(
+	ctx context.Context,
+	name string,
+	scopes []string,
+	library string,
+	ttl time.Duration) (*models.IssuedAPIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(secret)
+	issued := &models.IssuedAPIKey{
+		APIKey: models.APIKey{
+			Name:    name,
+			Prefix:  key[:len(APIKeyPrefix)+4],
+			KeyHash: hashAPIKey(key),
+			Scopes:  scopes,
+		},
+		Key: key,
+	}
+	if library != "" {
+		issued.Library = &library
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		issued.ExpiresAt = &expiresAt
+	}
+	if err := s.Repo.Save(ctx, &issued.APIKey); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save api key %s", name)
+		return nil, err
+	}
+	return issued, nil
+}
+
+// GetKeys returns keys bound to the library, keys of all libraries if library is empty
+func (s APIKeyService) GetKeys(ctx context.Context, library string) ([]models.APIKey, error) {
+	keys, err := s.Repo.GetAll(ctx, library)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get api keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey revokes the key if it is bound to the library, any key if library is empty
+func (s APIKeyService) RevokeKey(ctx context.Context, id int, library string) error {
+	if err := s.Repo.Revoke(ctx, id, library); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to revoke api key with id %d", id)
+		return err
+	}
+	return nil
+}
+
+// Authenticate finds the key and checks it is neither revoked nor expired, last usage of the key is recorded
+func (s APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, fmt.Errorf("unauthorized error: malformed api key")
+	}
+	apiKey, err := s.Repo.GetByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, fmt.Errorf("unauthorized error: unknown api key")
+		}
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get api key")
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, fmt.Errorf("unauthorized error: api key %s is revoked", apiKey.Prefix)
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("unauthorized error: api key %s is expired", apiKey.Prefix)
+	}
+	if err := s.Repo.Touch(ctx, *apiKey.ID); err != nil {
+		// Key is valid anyway
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to record usage of api key with id %d", *apiKey.ID)
+	}
+	return apiKey, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IArtistService interface {
+	CreateArtist(ctx context.Context, artist *models.Artist) error
+	GetArtists(ctx context.Context, page, limit int) ([]models.Artist, error)
+	GetArtist(ctx context.Context, id int) (*models.Artist, error)
+	UpdateArtist(ctx context.Context, artist *models.Artist) error
+	DeleteArtist(ctx context.Context, id int) error
+	GetArtistSongs(ctx context.Context, id, page, limit int) ([]models.Song, error)
+}
+
+type ArtistService struct {
+	Repo     repository.IArtistRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewArtistService(artistRepo repository.IArtistRepo, songRepo repository.ISongRepo) IArtistService {
+	return ArtistService{artistRepo, songRepo}
+}
+
+func (s ArtistService) CreateArtist(ctx context.Context, artist *models.Artist) error {
+	artist.ID = nil
+	if err := s.Repo.Save(ctx, artist); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save artist")
+		return err
+	}
+	return nil
+}
+
+func (s ArtistService) GetArtists(ctx context.Context, page, limit int) ([]models.Artist, error) {
+	offset := (page - 1) * limit
+	artists, err := s.Repo.GetAll(ctx, offset, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get artists")
+		return nil, err
+	}
+	return artists, nil
+}
+
+func (s ArtistService) GetArtist(ctx context.Context, id int) (*models.Artist, error) {
+	artist, err := s.Repo.GetById(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get artist with id %d", id)
+		return nil, err
+	}
+	return artist, nil
+}
+
+// UpdateArtist replaces name, aliases and country of the artist, songs of the artist are renamed with it
+func (s ArtistService) UpdateArtist(ctx context.Context, artist *models.Artist) error {
+	if err := s.Repo.Save(ctx, artist); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update artist with id %d", *artist.ID)
+		return err
+	}
+	return nil
+}
+
+func (s ArtistService) DeleteArtist(ctx context.Context, id int) error {
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete artist with id %d", id)
+		return err
+	}
+	return nil
+}
+
+// GetArtistSongs fetches songs of the artist using pagination
+func (s ArtistService) GetArtistSongs(ctx context.Context, id, page, limit int) ([]models.Song, error) {
+	if _, err := s.GetArtist(ctx, id); err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * limit
+	songs, err := s.SongRepo.GetFiltered(ctx, repository.SongFilter{ArtistID: id}, offset, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get songs of artist with id %d", id)
+		return nil, err
+	}
+	return songs, nil
+}
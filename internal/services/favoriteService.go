@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IFavoriteService interface {
+	GetFavorites(ctx context.Context, page, limit int) ([]models.Favorite, error)
+	AddFavorite(ctx context.Context, songID int) (*models.Favorite, error)
+	RemoveFavorite(ctx context.Context, songID int) error
+}
+
+// FavoriteService manages favorite songs of the actor of the request context
+type FavoriteService struct {
+	Repo     repository.IFavoriteRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewFavoriteService(favoriteRepo repository.IFavoriteRepo, songRepo repository.ISongRepo) IFavoriteService {
+	return FavoriteService{favoriteRepo, songRepo}
+}
+
+// GetFavorites fetches favorites of the actor with their songs using pagination, most recent first
+func (s FavoriteService) GetFavorites(ctx context.Context, page, limit int) ([]models.Favorite, error) {
+	offset := (page - 1) * limit
+	actor := utils.ActorFromContext(ctx)
+	favorites, err := s.Repo.GetByUser(ctx, actor, offset, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get favorites of %s", actor)
+		return nil, err
+	}
+	songIDs := make([]int, 0, len(favorites))
+	for _, favorite := range favorites {
+		songIDs = append(songIDs, favorite.SongID)
+	}
+	songs, err := s.SongRepo.GetByIds(ctx, songIDs)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get favorite songs of %s", actor)
+		return nil, err
+	}
+	songsByID := make(map[int]*models.Song, len(songs))
+	for i := range songs {
+		songsByID[*songs[i].ID] = &songs[i]
+	}
+	for i := range favorites {
+		favorites[i].Song = songsByID[favorites[i].SongID]
+	}
+	return favorites, nil
+}
+
+func (s FavoriteService) AddFavorite(ctx context.Context, songID int) (*models.Favorite, error) {
+	favorite := &models.Favorite{User: utils.ActorFromContext(ctx), SongID: songID}
+	if err := s.Repo.Save(ctx, favorite); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to add song with id %d to favorites", songID)
+		return nil, err
+	}
+	return favorite, nil
+}
+
+func (s FavoriteService) RemoveFavorite(ctx context.Context, songID int) error {
+	if err := s.Repo.Delete(ctx, utils.ActorFromContext(ctx), songID); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove song with id %d from favorites", songID)
+		return err
+	}
+	return nil
+}
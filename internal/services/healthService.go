@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/config"
+	"music-lib/internal/db/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Statuses of the service and its dependencies
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+type IHealthService interface {
+	Ready(ctx context.Context) *HealthReport
+}
+
+// HealthReport is the readiness of the service, it is up only if every dependency is up
+type HealthReport struct {
+	Status string                 `json:"status" example:"up"`
+	Checks map[string]HealthCheck `json:"checks"` // By dependency: database, migrations and music-info
+}
+
+type HealthCheck struct {
+	Status  string `json:"status" example:"up"`
+	Latency string `json:"latency" example:"1.2ms"`
+	Error   string `json:"error,omitempty" example:"connection refused"`
+}
+
+type HealthService struct {
+	Repo             repository.IHealthRepo
+	MusicInfoService IMusicInfoService
+	Timeout          time.Duration
+	ProbeExternalAPI bool
+}
+
+func NewHealthService(healthRepo repository.IHealthRepo, musicInfoS IMusicInfoService, cfg *config.Config) IHealthService {
+	timeout := time.Duration(cfg.Health.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return HealthService{healthRepo, musicInfoS, timeout, cfg.Health.ProbeExternalAPI}
+}
+
+// Ready checks the database connection and migrations, and the music info API if it is probed
+func (s HealthService) Ready(ctx context.Context) *HealthReport {
+	checks := map[string]func(context.Context) error{
+		"database":   s.Repo.Ping,
+		"migrations": s.Repo.CheckMigrations,
+	}
+	if s.ProbeExternalAPI {
+		checks["music-info"] = s.MusicInfoService.Ping
+	}
+	report := &HealthReport{Status: HealthUp, Checks: make(map[string]HealthCheck, len(checks))}
+	for name, check := range checks {
+		result := s.run(ctx, check)
+		if result.Status != HealthUp {
+			log.Ctx(ctx).Warn().Msgf("readiness check %s failed: %s", name, result.Error)
+			report.Status = HealthDown
+		}
+		report.Checks[name] = result
+	}
+	return report
+}
+
+// run runs the check with the configured timeout
+func (s HealthService) run(ctx context.Context, check func(context.Context) error) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	result := HealthCheck{Status: HealthUp, Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = HealthDown
+		result.Error = err.Error()
+	}
+	return result
+}
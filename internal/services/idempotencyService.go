@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IIdempotencyService interface {
+	Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, reservation *models.IdempotencyKey, status int, body []byte) error
+	Abort(ctx context.Context, reservation *models.IdempotencyKey) error
+	PurgeExpired(ctx context.Context) error
+}
+
+type IdempotencyService struct {
+	Repo  repository.IIdempotencyRepo
+	TTL   time.Duration // Responses are replayed for that long
+	Lease time.Duration // Keys of requests in progress are reserved for that long
+}
+
+func NewIdempotencyService(repo repository.IIdempotencyRepo, cfg *config.Config) IIdempotencyService {
+	ttl := time.Duration(cfg.Idempotency.TTL) * time.Hour
+	lease := time.Duration(cfg.Idempotency.Lease) * time.Second
+
+	return IdempotencyService{repo, ttl, lease}
+}
+
+// Begin reserves the key of the actor and library of ctx for a new request for the lease
+// and returns the reservation, which has no status code.
+// If the key was already used, the stored record is returned so its response can be replayed
+func (s IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyKey, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	reservation := &models.IdempotencyKey{Key: key, Token: hex.EncodeToString(token), Fingerprint: fingerprint}
+	reserved, err := s.Repo.Reserve(ctx, key, reservation.Token, fingerprint, time.Now().Add(s.Lease))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to reserve idempotency key %s", key)
+		return nil, err
+	}
+	if reserved {
+		return reservation, nil
+	}
+	record, err := s.Repo.Get(ctx, key)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get idempotency key %s", key)
+		return nil, err
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("mismatch error: idempotency key %s was used with a different request", key)
+	}
+	if record.StatusCode == nil {
+		return nil, fmt.Errorf("in progress error: request with idempotency key %s is still being processed", key)
+	}
+	return record, nil
+}
+
+// Complete stores the response of the request holding the reservation to replay it for the TTL
+func (s IdempotencyService) Complete(
+	ctx context.Context, reservation *models.IdempotencyKey, status int, body []byte,
+) error {
+	err := s.Repo.Complete(ctx, reservation.Key, reservation.Token, status, body, time.Now().Add(s.TTL))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to store response for idempotency key %s", reservation.Key)
+		return err
+	}
+	return nil
+}
+
+// Abort releases the reservation, so the request can be retried
+func (s IdempotencyService) Abort(ctx context.Context, reservation *models.IdempotencyKey) error {
+	if err := s.Repo.Delete(ctx, reservation.Key, reservation.Token); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to release idempotency key %s", reservation.Key)
+		return err
+	}
+	return nil
+}
+
+func (s IdempotencyService) PurgeExpired(ctx context.Context) error {
+	count, err := s.Repo.DeleteExpired(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to purge expired idempotency keys")
+		return err
+	}
+	log.Ctx(ctx).Debug().Msgf("purged %d expired idempotency keys", count)
+	return nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyRepo keeps records in memory by actor, library and key,
+// expired records are treated as missing like in the database
+type memoryIdempotencyRepo struct {
+	records map[string]*models.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{records: map[string]*models.IdempotencyKey{}}
+}
+
+// scopedKey returns the key of the record of the actor and library of ctx
+func scopedKey(ctx context.Context, key string) string {
+	return utils.ActorFromContext(ctx) + "/" + utils.LibraryFromContext(ctx) + "/" + key
+}
+
+func (r *memoryIdempotencyRepo) Reserve(ctx context.Context, key, token, fingerprint string, expiresAt time.Time) (bool, error) {
+	if record, ok := r.records[scopedKey(ctx, key)]; ok && record.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	r.records[scopedKey(ctx, key)] = &models.IdempotencyKey{
+		Actor:       utils.ActorFromContext(ctx),
+		Library:     utils.LibraryFromContext(ctx),
+		Key:         key,
+		Token:       token,
+		Fingerprint: fingerprint,
+		ExpiresAt:   expiresAt,
+	}
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepo) Get(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	record, ok := r.records[scopedKey(ctx, key)]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("not found error: idempotency key %s doesn't exist", key)
+	}
+	return record, nil
+}
+
+func (r *memoryIdempotencyRepo) Complete(
+	ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time,
+) error {
+	if record, ok := r.records[scopedKey(ctx, key)]; ok && record.Token == token && record.StatusCode == nil {
+		record.StatusCode = &status
+		record.ResponseBody = body
+		record.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) Delete(ctx context.Context, key, token string) error {
+	if record, ok := r.records[scopedKey(ctx, key)]; ok && record.Token == token && record.StatusCode == nil {
+		delete(r.records, scopedKey(ctx, key))
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryIdempotencyRepo()
+	s := IdempotencyService{Repo: repo, TTL: time.Hour, Lease: time.Minute}
+
+	reservation, err := s.Begin(ctx, "key", "fingerprint")
+	if err != nil || reservation.StatusCode != nil || reservation.Token == "" {
+		t.Fatalf("Expected key to be reserved, got %+v, %v", reservation, err)
+	}
+	if lease := time.Until(repo.records[scopedKey(ctx, "key")].ExpiresAt); lease > time.Minute {
+		t.Fatalf("Expected key to be reserved for the lease, got %v", lease)
+	}
+	// Repeated while the first request is in progress
+	if _, err := s.Begin(ctx, "key", "fingerprint"); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Fatalf("Expected in progress error, got %v", err)
+	}
+	// Same key with another request
+	if _, err := s.Begin(ctx, "key", "other"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("Expected mismatch error, got %v", err)
+	}
+
+	if err := s.Complete(ctx, reservation, 201, []byte(`{"message":"created"}`)); err != nil {
+		t.Fatalf("Error completing request: %v", err)
+	}
+	if ttl := time.Until(repo.records[scopedKey(ctx, "key")].ExpiresAt); ttl <= time.Minute {
+		t.Fatalf("Expected response to be kept for the TTL, got %v", ttl)
+	}
+	record, err := s.Begin(ctx, "key", "fingerprint")
+	if err != nil {
+		t.Fatalf("Error replaying request: %v", err)
+	}
+	if record.StatusCode == nil || *record.StatusCode != 201 || string(record.ResponseBody) != `{"message":"created"}` {
+		t.Fatalf("Expected stored response to be replayed, got %+v", record)
+	}
+	if _, err := s.Begin(ctx, "key", "other"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("Expected mismatch error after completion, got %v", err)
+	}
+}
+
+func TestIdempotencyAbort(t *testing.T) {
+	ctx := context.Background()
+	s := IdempotencyService{Repo: newMemoryIdempotencyRepo(), TTL: time.Hour, Lease: time.Minute}
+	reservation, err := s.Begin(ctx, "key", "fingerprint")
+	if err != nil {
+		t.Fatalf("Error reserving key: %v", err)
+	}
+	if err := s.Abort(ctx, reservation); err != nil {
+		t.Fatalf("Error releasing key: %v", err)
+	}
+	if record, err := s.Begin(ctx, "key", "fingerprint"); err != nil || record.StatusCode != nil {
+		t.Fatalf("Expected released key to be reserved again, got %+v, %v", record, err)
+	}
+}
+
+func TestIdempotencyLeaseExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryIdempotencyRepo()
+	s := IdempotencyService{Repo: repo, TTL: time.Hour, Lease: time.Minute}
+	stale, err := s.Begin(ctx, "key", "fingerprint")
+	if err != nil {
+		t.Fatalf("Error reserving key: %v", err)
+	}
+	// The request holding the key outlived its lease
+	repo.records[scopedKey(ctx, "key")].ExpiresAt = time.Now().Add(-time.Second)
+	reservation, err := s.Begin(ctx, "key", "fingerprint")
+	if err != nil || reservation.StatusCode != nil {
+		t.Fatalf("Expected key with expired lease to be reserved again, got %+v, %v", reservation, err)
+	}
+	// The stale request neither releases nor completes the key of the new one
+	if err := s.Abort(ctx, stale); err != nil {
+		t.Fatalf("Error releasing key: %v", err)
+	}
+	if err := s.Complete(ctx, stale, 500, nil); err != nil {
+		t.Fatalf("Error completing request: %v", err)
+	}
+	record := repo.records[scopedKey(ctx, "key")]
+	if record == nil || record.Token != reservation.Token || record.StatusCode != nil {
+		t.Fatalf("Expected key to stay reserved by the new request, got %+v", record)
+	}
+	if err := s.Complete(ctx, reservation, 201, []byte(`{}`)); err != nil {
+		t.Fatalf("Error completing request: %v", err)
+	}
+	if record.StatusCode == nil || *record.StatusCode != 201 {
+		t.Fatalf("Expected response of the new request to be stored, got %+v", record)
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	alice := utils.WithActor(context.Background(), "alice")
+	s := IdempotencyService{Repo: newMemoryIdempotencyRepo(), TTL: time.Hour, Lease: time.Minute}
+	reservation, err := s.Begin(alice, "key", "fingerprint")
+	if err != nil {
+		t.Fatalf("Error reserving key: %v", err)
+	}
+	if err := s.Complete(alice, reservation, 201, []byte(`{}`)); err != nil {
+		t.Fatalf("Error completing request: %v", err)
+	}
+	// The same key of another user or library is another key
+	others := []context.Context{
+		utils.WithActor(context.Background(), "bob"),
+		utils.WithLibrary(alice, "team-a"),
+	}
+	for _, ctx := range others {
+		record, err := s.Begin(ctx, "key", "other")
+		if err != nil || record.StatusCode != nil {
+			t.Fatalf("Expected key to be reserved for %s in %s, got %+v, %v",
+				utils.ActorFromContext(ctx), utils.LibraryFromContext(ctx), record, err)
+		}
+	}
+}
@@ -1,28 +1,57 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"music-lib/internal/config"
+	"music-lib/internal/metrics"
 	"music-lib/internal/utils"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/time/rate"
 )
 
 type IMusicInfoService interface {
-	GetSongInfo(artist, name string) (*SongDetail, error)
+	GetSongInfo(ctx context.Context, artist, name string) (*SongDetail, error)
+	Ping(ctx context.Context) error
 }
 
-// MusicInfoService is a an external service, that provides additional information about songs
+// Delay before the first retry of a failed request, it grows with every attempt
+const retryDelay = 200 * time.Millisecond
+
+// MusicInfoService is a an external service, that provides additional information about songs.
+// Its settings can be changed with Configure while requests are running
 type MusicInfoService struct {
+	settings  atomic.Pointer[musicInfoSettings]
+	transport http.RoundTripper
+}
+
+type musicInfoSettings struct {
 	baseURL string
+	retries int
 	client  *http.Client
+	queue   *musicInfoQueue
+}
+
+// musicInfoQueue holds requests to the service over its rate limit or the number of concurrent requests.
+// Its limits can be changed while requests are running
+type musicInfoQueue struct {
+	mu            sync.Mutex
+	limit         config.Limit
+	maxConcurrent int           // Concurrent requests are not limited if 0
+	limiter       *rate.Limiter // Allows every request if rate is not limited
+	inFlight      int           // Requests holding a slot
+	freed         chan struct{} // Closed and replaced when a slot is freed or limits change
 }
 
 type SongDetail struct {
@@ -32,19 +61,46 @@ type SongDetail struct {
 }
 
 func NewMusicInfoService(cfg *config.Config) (*MusicInfoService, error) {
-	cl := &http.Client{
-		Timeout: time.Duration(cfg.ExternalAPI.Timeout) * time.Second,
+	ms := &MusicInfoService{
+		// Every request gets a client span, trace context is passed to the service in traceparent header
+		transport: otelhttp.NewTransport(http.DefaultTransport),
 	}
+	ms.Configure(cfg)
+	return ms, nil
+}
 
-	return &MusicInfoService{
+// Configure applies base URL, timeout, retries and limits of the external API section of cfg to subsequent
+// requests, running requests are finished with previous settings. The queue is kept with new limits,
+// requests holding slots count towards the new number of concurrent requests
+func (ms *MusicInfoService) Configure(cfg *config.Config) {
+	limit, maxConcurrent := cfg.ExternalAPI.RateLimit, cfg.ExternalAPI.MaxConcurrent
+	var queue *musicInfoQueue
+	if prev := ms.settings.Load(); prev != nil {
+		queue = prev.queue
+		queue.configure(limit, maxConcurrent)
+	} else {
+		queue = newMusicInfoQueue(limit, maxConcurrent)
+	}
+	ms.settings.Store(&musicInfoSettings{
 		baseURL: cfg.ExternalAPI.BaseURL,
-		client:  cl,
-	}, nil
+		retries: cfg.ExternalAPI.Retries,
+		client: &http.Client{
+			Timeout:   time.Duration(cfg.ExternalAPI.Timeout) * time.Second,
+			Transport: ms.transport,
+		},
+		queue: queue,
+	})
 }
 
-func (ms *MusicInfoService) GetSongInfo(artist, name string) (*SongDetail, error) {
+// GetSongInfo fetches song details, the outcome and duration of every request is recorded in metrics.
+// The request is traced as a child of the span in ctx
+func (ms *MusicInfoService) GetSongInfo(ctx context.Context, artist, name string) (*SongDetail, error) {
+	start := time.Now()
+	outcome := metrics.OutcomeError
+	defer func() { metrics.ObserveUpstream(outcome, start) }()
+	settings := ms.settings.Load()
 	// Construct URL for the request
-	u, err := url.Parse(ms.baseURL)
+	u, err := url.Parse(settings.baseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -53,14 +109,27 @@ func (ms *MusicInfoService) GetSongInfo(artist, name string) (*SongDetail, error
 	queryParams.Add("group", artist)
 	queryParams.Add("song", name)
 	u.RawQuery = queryParams.Encode()
+	// Wait for a free slot, the slot is kept for retries
+	release, err := settings.queue.acquire(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("gave up waiting in queue for music info API")
+		return nil, err
+	}
+	defer release()
 	// Send request
-	log.Info().Msgf("Sending request to %s", u.String())
-	resp, err := ms.client.Get(u.String())
+	log.Ctx(ctx).Info().Msgf("Sending request to %s", u.String())
+	resp, err := ms.send(ctx, settings, u.String())
 	if err != nil {
-		log.Error().Err(err).Msg("failed to send request")
+		log.Ctx(ctx).Error().Err(err).Msg("failed to send request")
 		return nil, err
 	}
+	// Client span of the request ends when the body is closed
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
+		outcome = metrics.OutcomeStatus
+		if resp.StatusCode == http.StatusNotFound {
+			outcome = metrics.OutcomeNotFound
+		}
 		return nil, fmt.Errorf("%d", resp.StatusCode)
 	}
 	body, err := io.ReadAll(resp.Body)
@@ -70,14 +139,152 @@ func (ms *MusicInfoService) GetSongInfo(artist, name string) (*SongDetail, error
 
 	var songDetail SongDetail
 	if err := json.Unmarshal(body, &songDetail); err != nil {
-		log.Error().Err(err).Msg("failed to unmarshal response")
+		log.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal response")
+		outcome = metrics.OutcomeInvalid
 		return nil, fmt.Errorf("failed to unmarshal response")
 	}
 	if err := validator.New().Struct(songDetail); err != nil {
+		outcome = metrics.OutcomeInvalid
 		return nil, fmt.Errorf("invalid response: %v", err)
 	}
+	outcome = metrics.OutcomeSuccess
 
-	log.Debug().Msgf("Release date: %v", songDetail.ReleaseDate)
+	log.Ctx(ctx).Debug().Msgf("Release date: %v", songDetail.ReleaseDate)
 
 	return &songDetail, nil
 }
+
+// send makes GET request to the service, failed requests and server errors are retried
+// up to the configured number of times. Every attempt counts towards the rate limit
+func (ms *MusicInfoService) send(ctx context.Context, settings *musicInfoSettings, target string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := settings.queue.take(ctx); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		if id := utils.RequestIDFromContext(ctx); id != "" {
+			// Requests of the service can be found by the ID of the request to the API
+			req.Header.Set(utils.HeaderRequestID, id)
+		}
+		resp, err := settings.client.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+		if attempt > settings.retries || ctx.Err() != nil {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		log.Ctx(ctx).Warn().Err(err).Msgf("request to %s failed, retry %d of %d", target, attempt, settings.retries)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryDelay * time.Duration(attempt)):
+		}
+	}
+}
+
+// Ping checks the service is reachable, any response except server errors counts.
+// Pings are not limited, so readiness doesn't depend on the load of the queue
+func (ms *MusicInfoService) Ping(ctx context.Context) error {
+	settings := ms.settings.Load()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, settings.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := settings.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newMusicInfoQueue(limit config.Limit, maxConcurrent int) *musicInfoQueue {
+	q := &musicInfoQueue{freed: make(chan struct{})}
+	q.configure(limit, maxConcurrent)
+	return q
+}
+
+// configure changes limits of the queue. Tokens of the rate limiter are kept up to the new burst
+// unless rate wasn't limited before, queued requests are woken up to check the new limits
+func (q *musicInfoQueue) configure(limit config.Limit, maxConcurrent int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case limit.Rate <= 0:
+		q.limiter = rate.NewLimiter(rate.Inf, 0)
+	case q.limiter == nil || q.limit.Rate <= 0:
+		q.limiter = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	default:
+		q.limiter.SetLimit(rate.Limit(limit.Rate))
+		q.limiter.SetBurst(limit.Burst)
+	}
+	q.limit = limit
+	q.maxConcurrent = maxConcurrent
+	q.wake()
+}
+
+// wake lets queued requests check for a free slot again, q.mu should be held
+func (q *musicInfoQueue) wake() {
+	close(q.freed)
+	q.freed = make(chan struct{})
+}
+
+// acquire waits for a slot among concurrent requests until ctx is done, returned function frees the slot
+func (q *musicInfoQueue) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+	for {
+		q.mu.Lock()
+		if q.maxConcurrent <= 0 || q.inFlight < q.maxConcurrent {
+			q.inFlight++
+			q.mu.Unlock()
+			logQueueWait(ctx, "slot", time.Since(start))
+			return q.release, nil
+		}
+		freed := q.freed
+		q.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *musicInfoQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight--
+	q.wake()
+}
+
+// take waits for the rate limit to allow a request, fails at once if it can't be allowed before ctx deadline
+func (q *musicInfoQueue) take(ctx context.Context) error {
+	q.mu.Lock()
+	limiter := q.limiter
+	q.mu.Unlock()
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+	logQueueWait(ctx, "rate limit", time.Since(start))
+	return nil
+}
+
+// logQueueWait logs noticeable waits at info level, so a queue building up is seen in logs
+func logQueueWait(ctx context.Context, reason string, wait time.Duration) {
+	event := log.Ctx(ctx).Debug()
+	if wait >= 10*time.Millisecond {
+		event = log.Ctx(ctx).Info()
+	}
+	event.Dur("queue_wait", wait).Msgf("Waited for %s of music info API", reason)
+}
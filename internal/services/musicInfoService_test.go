@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"music-lib/internal/config"
+	"music-lib/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acquireWithin tries to get a slot of the queue for the duration
+func acquireWithin(q *musicInfoQueue, d time.Duration) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return q.acquire(ctx)
+}
+
+func TestMusicInfoQueueConcurrency(t *testing.T) {
+	q := newMusicInfoQueue(config.Limit{}, 2)
+	first, err := acquireWithin(q, time.Second)
+	if err != nil {
+		t.Fatalf("Error acquiring slot: %v", err)
+	}
+	if _, err := acquireWithin(q, time.Second); err != nil {
+		t.Fatalf("Error acquiring slot: %v", err)
+	}
+	if _, err := acquireWithin(q, 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected request over the limit to wait until its deadline, got %v", err)
+	}
+	// Queued request gets the slot once it is freed
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := acquireWithin(q, time.Second)
+		acquired <- err
+	}()
+	first()
+	if err := <-acquired; err != nil {
+		t.Fatalf("Expected queued request to get the freed slot, got %v", err)
+	}
+}
+
+func TestMusicInfoQueueUnlimited(t *testing.T) {
+	q := newMusicInfoQueue(config.Limit{}, 0)
+	for i := 0; i < 100; i++ {
+		if _, err := acquireWithin(q, 10*time.Millisecond); err != nil {
+			t.Fatalf("Expected unlimited queue to allow request %d, got %v", i+1, err)
+		}
+		if err := q.take(context.Background()); err != nil {
+			t.Fatalf("Expected unlimited rate to allow request %d, got %v", i+1, err)
+		}
+	}
+}
+
+func TestMusicInfoQueueCanceled(t *testing.T) {
+	q := newMusicInfoQueue(config.Limit{}, 1)
+	if _, err := acquireWithin(q, time.Second); err != nil {
+		t.Fatalf("Error acquiring slot: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() {
+		_, err := q.acquire(ctx)
+		queued <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled request to leave the queue, got %v", err)
+	}
+	// Canceled request doesn't hold a slot
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight != 1 {
+		t.Fatalf("Expected 1 request in flight, got %d", q.inFlight)
+	}
+}
+
+func TestMusicInfoQueueRate(t *testing.T) {
+	q := newMusicInfoQueue(config.Limit{Rate: 20, Burst: 1}, 0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := q.take(context.Background()); err != nil {
+			t.Fatalf("Error waiting for rate limit: %v", err)
+		}
+	}
+	// The first request uses the burst, the others wait 50ms each
+	if waited := time.Since(start); waited < 90*time.Millisecond {
+		t.Fatalf("Expected requests to wait for the rate limit, waited %v", waited)
+	}
+	// Request that can't be allowed before its deadline fails at once
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := q.take(ctx); err == nil {
+		t.Fatalf("Expected request to fail when rate limit allows it after the deadline")
+	}
+	if waited := time.Since(start); waited >= 10*time.Millisecond {
+		t.Fatalf("Expected request to fail without waiting, waited %v", waited)
+	}
+}
+
+func TestMusicInfoQueueConfigure(t *testing.T) {
+	q := newMusicInfoQueue(config.Limit{}, 2)
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := acquireWithin(q, time.Second)
+		if err != nil {
+			t.Fatalf("Error acquiring slot: %v", err)
+		}
+		releases = append(releases, release)
+	}
+	// Queued request is woken up when the limit is raised
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := acquireWithin(q, time.Second)
+		releases = append(releases, release)
+		acquired <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.configure(config.Limit{}, 3)
+	if err := <-acquired; err != nil {
+		t.Fatalf("Expected queued request to get a slot of the raised limit, got %v", err)
+	}
+	// Requests in flight count towards the lowered limit
+	q.configure(config.Limit{}, 1)
+	releases[0]()
+	releases[1]()
+	if _, err := acquireWithin(q, 20*time.Millisecond); err == nil {
+		t.Fatalf("Expected request to wait while requests in flight use the slots")
+	}
+	releases[2]()
+	if _, err := acquireWithin(q, time.Second); err != nil {
+		t.Fatalf("Expected slot once requests in flight are finished, got %v", err)
+	}
+}
+
+func TestMusicInfoServiceConfigureKeepsQueue(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ExternalAPI.BaseURL = "http://localhost:8088"
+	cfg.ExternalAPI.MaxConcurrent = 1
+	ms, err := NewMusicInfoService(cfg)
+	if err != nil {
+		t.Fatalf("Error creating service: %v", err)
+	}
+	queue := ms.settings.Load().queue
+	if _, err := acquireWithin(queue, time.Second); err != nil {
+		t.Fatalf("Error acquiring slot: %v", err)
+	}
+	cfg.ExternalAPI.MaxConcurrent = 2
+	ms.Configure(cfg)
+	if reloaded := ms.settings.Load().queue; reloaded != queue || reloaded.inFlight != 1 {
+		t.Fatalf("Expected queue to be kept with requests in flight")
+	}
+}
+
+func TestMusicInfoRequestID(t *testing.T) {
+	mu := sync.Mutex{}
+	var received []string
+	attempts := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get(utils.HeaderRequestID))
+		attempts++
+		if attempts == 1 {
+			// The first attempt fails and is retried
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"releaseDate": "16.07.2006", "text": "Lyrics", "link": "https://example.com"}`))
+	}))
+	defer upstream.Close()
+	cfg := &config.Config{}
+	cfg.ExternalAPI.BaseURL = upstream.URL
+	cfg.ExternalAPI.Timeout = 5
+	cfg.ExternalAPI.Retries = 1
+	ms, err := NewMusicInfoService(cfg)
+	if err != nil {
+		t.Fatalf("Error creating service: %v", err)
+	}
+
+	ctx := utils.WithRequestID(context.Background(), "client-id")
+	if _, err := ms.GetSongInfo(ctx, "Muse", "Supermassive Black Hole"); err != nil {
+		t.Fatalf("Error getting song info: %v", err)
+	}
+	if _, err := ms.GetSongInfo(context.Background(), "Muse", "Starlight"); err != nil {
+		t.Fatalf("Error getting song info: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Every attempt carries the request ID, requests outside of API requests have none
+	if len(received) != 3 || received[0] != "client-id" || received[1] != "client-id" || received[2] != "" {
+		t.Fatalf("Unexpected request IDs received by the service %q", received)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IPlaylistService interface {
+	CreatePlaylist(ctx context.Context, playlist *models.Playlist) error
+	GetPlaylists(ctx context.Context, page, limit int) ([]models.Playlist, error)
+	GetPlaylist(ctx context.Context, id int) (*models.Playlist, error)
+	UpdatePlaylist(ctx context.Context, playlist *models.Playlist) error
+	DeletePlaylist(ctx context.Context, id int) error
+	AddEntry(ctx context.Context, entry *models.PlaylistEntry) (*models.Playlist, error)
+	MoveEntry(ctx context.Context, entry *models.PlaylistEntry) (*models.Playlist, error)
+	RemoveEntry(ctx context.Context, id, entryID int) (*models.Playlist, error)
+}
+
+// PlaylistService manages playlists on behalf of the actor of the request context,
+// who owns the playlists it creates and is the only one allowed to change them
+type PlaylistService struct {
+	Repo     repository.IPlaylistRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewPlaylistService(playlistRepo repository.IPlaylistRepo, songRepo repository.ISongRepo) IPlaylistService {
+	return PlaylistService{playlistRepo, songRepo}
+}
+
+func (s PlaylistService) CreatePlaylist(ctx context.Context, playlist *models.Playlist) error {
+	playlist.ID = nil
+	playlist.Owner = utils.ActorFromContext(ctx)
+	if playlist.Visibility == "" {
+		playlist.Visibility = models.VisibilityPrivate
+	}
+	if err := s.Repo.Save(ctx, playlist); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save playlist")
+		return err
+	}
+	return nil
+}
+
+// GetPlaylists fetches public playlists and private playlists of the actor using pagination
+func (s PlaylistService) GetPlaylists(ctx context.Context, page, limit int) ([]models.Playlist, error) {
+	offset := (page - 1) * limit
+	playlists, err := s.Repo.GetVisible(ctx, utils.ActorFromContext(ctx), offset, limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get playlists")
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// GetPlaylist fetches the playlist with its entries, songs in trash are left out of the entries
+func (s PlaylistService) GetPlaylist(ctx context.Context, id int) (*models.Playlist, error) {
+	playlist, err := s.getVisible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Repo.GetEntries(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get entries of playlist with id %d", id)
+		return nil, err
+	}
+	songIDs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		songIDs = append(songIDs, entry.SongID)
+	}
+	songs, err := s.SongRepo.GetByIds(ctx, songIDs)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get songs of playlist with id %d", id)
+		return nil, err
+	}
+	songsByID := make(map[int]*models.Song, len(songs))
+	for i := range songs {
+		songsByID[*songs[i].ID] = &songs[i]
+	}
+	playlist.Entries = []models.PlaylistEntry{}
+	for _, entry := range entries {
+		if song, ok := songsByID[entry.SongID]; ok {
+			entry.Song = song
+			playlist.Entries = append(playlist.Entries, entry)
+		}
+	}
+	return playlist, nil
+}
+
+// UpdatePlaylist replaces name, description and visibility of the playlist
+func (s PlaylistService) UpdatePlaylist(ctx context.Context, playlist *models.Playlist) error {
+	if _, err := s.getOwned(ctx, *playlist.ID); err != nil {
+		return err
+	}
+	if playlist.Visibility == "" {
+		playlist.Visibility = models.VisibilityPrivate
+	}
+	if err := s.Repo.Save(ctx, playlist); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update playlist with id %d", *playlist.ID)
+		return err
+	}
+	return nil
+}
+
+func (s PlaylistService) DeletePlaylist(ctx context.Context, id int) error {
+	if _, err := s.getOwned(ctx, id); err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete playlist with id %d", id)
+		return err
+	}
+	return nil
+}
+
+// AddEntry inserts the song into the playlist and returns the playlist with its entries
+func (s PlaylistService) AddEntry(ctx context.Context, entry *models.PlaylistEntry) (*models.Playlist, error) {
+	if _, err := s.getOwned(ctx, entry.PlaylistID); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.InsertEntry(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to add song %d to playlist %d", entry.SongID, entry.PlaylistID)
+		return nil, err
+	}
+	return s.GetPlaylist(ctx, entry.PlaylistID)
+}
+
+// MoveEntry moves the entry to a new position and returns the playlist with its entries
+func (s PlaylistService) MoveEntry(ctx context.Context, entry *models.PlaylistEntry) (*models.Playlist, error) {
+	if _, err := s.getOwned(ctx, entry.PlaylistID); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.MoveEntry(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to move entry %d of playlist %d", entry.ID, entry.PlaylistID)
+		return nil, err
+	}
+	return s.GetPlaylist(ctx, entry.PlaylistID)
+}
+
+// RemoveEntry removes the entry and returns the playlist with remaining entries
+func (s PlaylistService) RemoveEntry(ctx context.Context, id, entryID int) (*models.Playlist, error) {
+	if _, err := s.getOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.RemoveEntry(ctx, id, entryID); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove entry %d from playlist %d", entryID, id)
+		return nil, err
+	}
+	return s.GetPlaylist(ctx, id)
+}
+
+// getVisible fetches the playlist if it is public or owned by the actor,
+// private playlists of others are reported as not found
+func (s PlaylistService) getVisible(ctx context.Context, id int) (*models.Playlist, error) {
+	playlist, err := s.Repo.GetById(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get playlist with id %d", id)
+		return nil, err
+	}
+	if playlist.Visibility != models.VisibilityPublic && playlist.Owner != utils.ActorFromContext(ctx) {
+		return nil, fmt.Errorf("not found error: playlist with id %d doesn't exist", id)
+	}
+	return playlist, nil
+}
+
+// getOwned fetches the playlist if the actor owns it
+func (s PlaylistService) getOwned(ctx context.Context, id int) (*models.Playlist, error) {
+	playlist, err := s.getVisible(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if playlist.Owner != utils.ActorFromContext(ctx) {
+		return nil, fmt.Errorf("forbidden error: playlist with id %d is owned by %s", id, playlist.Owner)
+	}
+	return playlist, nil
+}
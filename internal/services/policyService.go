@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/utils"
+	"slices"
+)
+
+// Actions on songs checked by the policy
+const (
+	ActionSongCreate     = "song.create"
+	ActionSongUpdate     = "song.update"
+	ActionSongEditLyrics = "song.edit-lyrics"
+	ActionSongDelete     = "song.delete"
+	ActionSongRestore    = "song.restore"
+	ActionSongRevert     = "song.revert"
+	ActionSongRefresh    = "song.refresh"
+	ActionSongBulk       = "song.bulk"
+)
+
+// Roles ordered from the least to the most privileged
+var roles = []string{utils.RoleViewer, utils.RoleEditor, utils.RoleModerator, utils.RoleAdmin}
+
+var actions = []string{
+	ActionSongCreate,
+	ActionSongUpdate,
+	ActionSongEditLyrics,
+	ActionSongDelete,
+	ActionSongRestore,
+	ActionSongRevert,
+	ActionSongRefresh,
+	ActionSongBulk,
+}
+
+type IPolicyService interface {
+	Authorize(ctx context.Context, action string) error
+	AuthorizeSongUpdate(ctx context.Context, song, newSong *models.Song) error
+}
+
+// PolicyService allows an action to the role of the request context if it is at least the role
+// required by the policy of the action. Admin is allowed everything, anonymous requests nothing
+type PolicyService struct {
+	Policies map[string]string
+}
+
+func NewPolicyService(cfg *config.Config) (IPolicyService, error) {
+	for action, role := range cfg.RBAC.Policies {
+		if !slices.Contains(actions, action) {
+			return nil, fmt.Errorf("rbac: unknown action %s", action)
+		}
+		if !IsRole(role) {
+			return nil, fmt.Errorf("rbac: unknown role %s of action %s", role, action)
+		}
+	}
+	return PolicyService{cfg.RBAC.Policies}, nil
+}
+
+// IsRole reports whether role is one of known roles
+func IsRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+func (s PolicyService) Authorize(ctx context.Context, action string) error {
+	if utils.ActorFromContext(ctx) == utils.AnonymousActor {
+		return fmt.Errorf("forbidden error: %s is not allowed for anonymous requests", action)
+	}
+	role := utils.RoleFromContext(ctx)
+	required, ok := s.Policies[action]
+	if !ok {
+		required = utils.RoleAdmin
+	}
+	if slices.Index(roles, role) < slices.Index(roles, required) {
+		return fmt.Errorf("forbidden error: %s is not allowed for role %s, %s is required", action, role, required)
+	}
+	return nil
+}
+
+// AuthorizeSongUpdate checks the update of song to newSong, changing lyrics is a separate action
+func (s PolicyService) AuthorizeSongUpdate(ctx context.Context, song, newSong *models.Song) error {
+	if err := s.Authorize(ctx, ActionSongUpdate); err != nil {
+		return err
+	}
+	if newSong.Lyrics != song.Lyrics {
+		return s.Authorize(ctx, ActionSongEditLyrics)
+	}
+	return nil
+}
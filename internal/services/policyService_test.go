@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/config"
+	"music-lib/internal/db/models"
+	"music-lib/internal/utils"
+	"testing"
+)
+
+func newTestPolicyService(t *testing.T) IPolicyService {
+	cfg := &config.Config{}
+	cfg.RBAC.Policies = map[string]string{
+		ActionSongCreate:     utils.RoleEditor,
+		ActionSongUpdate:     utils.RoleEditor,
+		ActionSongEditLyrics: utils.RoleModerator,
+		ActionSongDelete:     utils.RoleModerator,
+		ActionSongRefresh:    utils.RoleViewer,
+	}
+	s, err := NewPolicyService(cfg)
+	if err != nil {
+		t.Fatalf("Error creating policy service: %v", err)
+	}
+	return s
+}
+
+func roleContext(role string) context.Context {
+	ctx := utils.WithActor(context.Background(), "tester")
+	return utils.WithRole(ctx, role)
+}
+
+func TestAuthorize(t *testing.T) {
+	s := newTestPolicyService(t)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		action  string
+		allowed bool
+	}{
+		{"viewer can't create", roleContext(utils.RoleViewer), ActionSongCreate, false},
+		{"editor creates", roleContext(utils.RoleEditor), ActionSongCreate, true},
+		{"moderator creates", roleContext(utils.RoleModerator), ActionSongCreate, true},
+		{"editor can't delete", roleContext(utils.RoleEditor), ActionSongDelete, false},
+		{"moderator deletes", roleContext(utils.RoleModerator), ActionSongDelete, true},
+		{"admin deletes", roleContext(utils.RoleAdmin), ActionSongDelete, true},
+		{"viewer refreshes", roleContext(utils.RoleViewer), ActionSongRefresh, true},
+		{"moderator can't revert without policy", roleContext(utils.RoleModerator), ActionSongRevert, false},
+		{"admin reverts without policy", roleContext(utils.RoleAdmin), ActionSongRevert, true},
+		{"unknown role can't refresh", roleContext("owner"), ActionSongRefresh, false},
+		{"anonymous can't refresh", context.Background(), ActionSongRefresh, false},
+		{"anonymous with role can't refresh", utils.WithRole(context.Background(), utils.RoleAdmin), ActionSongRefresh, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Authorize(tt.ctx, tt.action)
+			if tt.allowed && err != nil {
+				t.Fatalf("Expected %s to be allowed, got %v", tt.action, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Fatalf("Expected %s to be forbidden, got nil", tt.action)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSongUpdate(t *testing.T) {
+	s := newTestPolicyService(t)
+	song := &models.Song{Name: "Song Name", Lyrics: "Song Lyrics"}
+	renamed := &models.Song{Name: "New Name", Lyrics: "Song Lyrics"}
+	rewritten := &models.Song{Name: "Song Name", Lyrics: "New Lyrics"}
+	tests := []struct {
+		name    string
+		role    string
+		newSong *models.Song
+		allowed bool
+	}{
+		{"viewer can't rename", utils.RoleViewer, renamed, false},
+		{"editor renames", utils.RoleEditor, renamed, true},
+		{"editor can't edit lyrics", utils.RoleEditor, rewritten, false},
+		{"moderator edits lyrics", utils.RoleModerator, rewritten, true},
+		{"admin edits lyrics", utils.RoleAdmin, rewritten, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.AuthorizeSongUpdate(roleContext(tt.role), song, tt.newSong)
+			if tt.allowed && err != nil {
+				t.Fatalf("Expected update to be allowed, got %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Fatalf("Expected update to be forbidden, got nil")
+			}
+		})
+	}
+}
+
+func TestNewPolicyServiceInvalid(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RBAC.Policies = map[string]string{"song.sing": utils.RoleEditor}
+	if _, err := NewPolicyService(cfg); err == nil {
+		t.Fatal("Expected error for unknown action")
+	}
+	cfg.RBAC.Policies = map[string]string{ActionSongCreate: "owner"}
+	if _, err := NewPolicyService(cfg); err == nil {
+		t.Fatal("Expected error for unknown role")
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+type IRatingService interface {
+	RateSong(ctx context.Context, songID, rating int) (*models.Song, error)
+	RemoveRating(ctx context.Context, songID int) (*models.Song, error)
+}
+
+// RatingService rates songs on behalf of the actor of the request context, every user has one rating per song
+type RatingService struct {
+	Repo     repository.IRatingRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewRatingService(ratingRepo repository.IRatingRepo, songRepo repository.ISongRepo) IRatingService {
+	return RatingService{ratingRepo, songRepo}
+}
+
+// RateSong sets rating of the actor and returns the song with updated average rating
+func (s RatingService) RateSong(ctx context.Context, songID, rating int) (*models.Song, error) {
+	r := &models.Rating{User: utils.ActorFromContext(ctx), SongID: songID, Rating: rating}
+	if err := s.Repo.Save(ctx, r); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to rate song with id %d", songID)
+		return nil, err
+	}
+	return s.getSong(ctx, songID)
+}
+
+// RemoveRating removes rating of the actor and returns the song with updated average rating
+func (s RatingService) RemoveRating(ctx context.Context, songID int) (*models.Song, error) {
+	if err := s.Repo.Delete(ctx, utils.ActorFromContext(ctx), songID); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove rating of song with id %d", songID)
+		return nil, err
+	}
+	return s.getSong(ctx, songID)
+}
+
+func (s RatingService) getSong(ctx context.Context, songID int) (*models.Song, error) {
+	song, err := s.SongRepo.GetById(ctx, songID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get song with id %d", songID)
+		return nil, err
+	}
+	return song, nil
+}
@@ -1,12 +1,21 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"music-lib/internal/db/models"
 	"music-lib/internal/db/repository"
+	"music-lib/internal/tracing"
 	"music-lib/internal/utils"
+	"reflect"
+	"sort"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
 )
 
@@ -14,10 +23,21 @@ type ISongService interface {
 	CreateSong(ctx context.Context, song *models.Song) error
 	GetSongs(ctx context.Context, f repository.SongFilter, page, limit int) ([]models.Song, error)
 	GetSong(ctx context.Context, id int) (*models.Song, error)
-	UpdateSong(ctx context.Context, song, newSong *models.Song) (*models.Song, error)
+	UpdateSong(ctx context.Context, id int, newSong *models.Song, authorize AuthorizeUpdate) (*models.Song, error)
+	PatchSong(ctx context.Context, id int, patchType string, patch []byte, authorize AuthorizeUpdate) (*models.Song, error)
+	ReplaceSong(ctx context.Context, song *models.Song) (bool, error)
 	DeleteSong(ctx context.Context, id int) error
+	GetTrash(ctx context.Context, page, limit int) ([]models.Song, error)
+	GetTrashedSong(ctx context.Context, id int) (*models.Song, error)
+	RestoreSong(ctx context.Context, id int) (*models.Song, error)
+	PurgeTrash(ctx context.Context, retention time.Duration) error
+	GetHistory(ctx context.Context, id int) ([]models.SongRevision, error)
+	RevertSong(ctx context.Context, id, revision int) (*models.Song, error)
 }
 
+// AuthorizeUpdate checks the update of the stored song to newSong before it is saved
+type AuthorizeUpdate func(song, newSong *models.Song) error
+
 type SongService struct {
 	Repo repository.ISongRepo
 }
@@ -27,21 +47,27 @@ func NewSongService(songRepo repository.ISongRepo) ISongService {
 }
 
 func (s SongService) CreateSong(ctx context.Context, song *models.Song) error {
+	ctx, span := tracing.Start(ctx, "SongService.CreateSong")
+	defer span.End()
 	if song.ID != nil {
 		return fmt.Errorf("ID should not be set for a new song")
 	}
 	err := s.Repo.Save(ctx, song)
 	if err != nil {
-		log.Logger.Error().Err(err).Msgf("failed to save song")
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save song")
 		return err
 	}
 	return nil
 }
 
 func (s SongService) GetSong(ctx context.Context, id int) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetSong")
+	defer span.End()
 	song, err := s.Repo.GetById(ctx, id)
 	if err != nil {
-		log.Logger.Error().Err(err).Msgf("failed to get song with id %d", id)
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get song with id %d", id)
 		return nil, err
 	}
 	return song, nil
@@ -49,19 +75,45 @@ func (s SongService) GetSong(ctx context.Context, id int) (*models.Song, error)
 
 // GetSongs fetches songs from database using filter and pagination
 func (s SongService) GetSongs(ctx context.Context, f repository.SongFilter, page, limit int) ([]models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetSongs")
+	defer span.End()
 	// Calculate offset
 	offset := (page - 1) * limit
-    log.Logger.Debug().Msgf("limit: %d, offset: %d", limit, offset)
+    log.Ctx(ctx).Debug().Msgf("limit: %d, offset: %d", limit, offset)
 	songs, err := s.Repo.GetFiltered(ctx, f, offset, limit)
 	if err != nil {
-		log.Logger.Error().Err(err).Msgf("failed to get songs")
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get songs")
 		return nil, err
 	}
 
 	return songs, nil
 }
 
-func (s SongService) UpdateSong(ctx context.Context, song, newSong *models.Song) (*models.Song, error) {
+// UpdateSong sets non-empty fields of newSong on the song with the given id.
+// The song is locked from reading it until it is saved, so concurrent updates aren't lost
+func (s SongService) UpdateSong(
+	ctx context.Context, id int, newSong *models.Song, authorize AuthorizeUpdate,
+) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.UpdateSong")
+	defer span.End()
+	updated, err := s.Repo.Update(ctx, id, func(song *models.Song) (*models.Song, error) {
+		updated := mergeSong(*song, newSong)
+		if err := authorize(song, updated); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	})
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update song with id %d", id)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// mergeSong returns song with non-empty fields of newSong
+func mergeSong(song models.Song, newSong *models.Song) *models.Song {
 	// Update provided fields
 	if newSong.Artist != "" {
 		song.Artist = newSong.Artist
@@ -80,19 +132,276 @@ func (s SongService) UpdateSong(ctx context.Context, song, newSong *models.Song)
 	if newSong.URL != "" {
 		song.URL = newSong.URL
 	}
-	// Save updated song
-	if err := s.Repo.Save(ctx, song); err != nil {
-		log.Logger.Error().Err(err).Msgf("failed to update song with id %d", song.ID)
+	// Stored credits and genres are kept unless new ones are provided
+	song.Credits = newSong.Credits
+	song.Genres = newSong.Genres
+	return &song
+}
+
+// ReplaceSong replaces the song with song.ID or creates it with that id, returns true if the song was created
+func (s SongService) ReplaceSong(ctx context.Context, song *models.Song) (bool, error) {
+	ctx, span := tracing.Start(ctx, "SongService.ReplaceSong")
+	defer span.End()
+	if song.ID == nil {
+		return false, fmt.Errorf("ID should be set to replace a song")
+	}
+	created, err := s.Repo.Upsert(ctx, song)
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to replace song with id %d", *song.ID)
+		return false, err
+	}
+	return created, nil
+}
+
+// ApplyPatch applies JSON Merge Patch or JSON Patch document to the song and returns the validated result
+func (s SongService) ApplyPatch(song *models.Song, patchType string, patch []byte) (*models.Song, error) {
+	original, err := json.Marshal(song)
+	if err != nil {
 		return nil, err
 	}
-	return song, nil
+	patched, err := applyPatch(original, patchType, patch)
+	if err != nil {
+		return nil, err
+	}
+	// Decode and validate patched document
+	doc := utils.SongDocument{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid patch error: %v", err)
+	}
+	if doc.ID == nil || *doc.ID != *song.ID {
+		return nil, fmt.Errorf("invalid patch error: id can't be changed")
+	}
+	if err := validator.New().Struct(doc); err != nil {
+		return nil, err
+	}
+	newSong := &models.Song{
+		ID:          song.ID,
+		Artist:      doc.Group,
+		Name:        doc.Song,
+		Lyrics:      doc.Lyrics,
+		ReleaseDate: doc.ReleaseDate,
+		URL:         doc.URL,
+		Credits:     []models.Credit{},
+		Genres:      models.NormalizeLabels(doc.Genres),
+	}
+	if newSong.Genres == nil {
+		// Genres removed by the patch
+		newSong.Genres = []string{}
+	}
+	// Primary credit of the original artist is implied by group, it isn't kept if group is patched
+	for _, credit := range models.CreditsFromRequest(doc.Credits) {
+		if credit.Artist == song.Artist && credit.Role == models.RolePrimary {
+			continue
+		}
+		newSong.Credits = append(newSong.Credits, credit)
+	}
+	return newSong, nil
+}
+
+// PatchSong applies the patch to the song with the given id and saves the result.
+// Unlike UpdateSong, fields set to empty values by the patch are cleared.
+// The song is locked from reading it until it is saved, so the patch applies to the stored song
+func (s SongService) PatchSong(
+	ctx context.Context, id int, patchType string, patch []byte, authorize AuthorizeUpdate,
+) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.PatchSong")
+	defer span.End()
+	patched, err := s.Repo.Update(ctx, id, func(song *models.Song) (*models.Song, error) {
+		patched, err := s.ApplyPatch(song, patchType, patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := authorize(song, patched); err != nil {
+			return nil, err
+		}
+		return patched, nil
+	})
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch song with id %d", id)
+		return nil, err
+	}
+	return patched, nil
+}
+
+func applyPatch(doc []byte, patchType string, patch []byte) ([]byte, error) {
+	switch patchType {
+	case utils.MIMEMergePatch:
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch error: %v", err)
+		}
+		return patched, nil
+	case utils.MIMEJSONPatch:
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch error: %v", err)
+		}
+		patched, err := p.Apply(doc)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return nil, fmt.Errorf("conflict error: %v", err)
+			}
+			return nil, fmt.Errorf("invalid patch error: %v", err)
+		}
+		return patched, nil
+	default:
+		return nil, fmt.Errorf("invalid patch error: unsupported patch type %s", patchType)
+	}
 }
 
 func (s SongService) DeleteSong(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "SongService.DeleteSong")
+	defer span.End()
 	err := s.Repo.Delete(ctx, id)
 	if err != nil {
-		log.Logger.Error().Err(err).Msgf("failed to delete song with id %d", id)
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete song with id %d", id)
 		return err
 	}
 	return nil
 }
+
+// GetTrash fetches deleted songs using pagination
+func (s SongService) GetTrash(ctx context.Context, page, limit int) ([]models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetTrash")
+	defer span.End()
+	offset := (page - 1) * limit
+	songs, err := s.Repo.GetTrashed(ctx, offset, limit)
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get deleted songs")
+		return nil, err
+	}
+	return songs, nil
+}
+
+// GetTrashedSong returns the song with the given id if it is in trash
+func (s SongService) GetTrashedSong(ctx context.Context, id int) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetTrashedSong")
+	defer span.End()
+	song, err := s.Repo.GetTrashedById(ctx, id)
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get deleted song with id %d", id)
+		return nil, err
+	}
+	return song, nil
+}
+
+func (s SongService) RestoreSong(ctx context.Context, id int) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.RestoreSong")
+	defer span.End()
+	if err := s.Repo.Restore(ctx, id); err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to restore song with id %d", id)
+		return nil, err
+	}
+	return s.GetSong(ctx, id)
+}
+
+// PurgeTrash permanently deletes songs that have been in trash longer than retention
+func (s SongService) PurgeTrash(ctx context.Context, retention time.Duration) error {
+	ctx, span := tracing.Start(ctx, "SongService.PurgeTrash")
+	defer span.End()
+	count, err := s.Repo.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msg("failed to purge deleted songs")
+		return err
+	}
+	if count > 0 {
+		log.Ctx(ctx).Info().Msgf("purged %d songs from trash", count)
+	}
+	return nil
+}
+
+// GetHistory returns revisions of the song with field changes relative to the previous revision
+func (s SongService) GetHistory(ctx context.Context, id int) ([]models.SongRevision, error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetHistory")
+	defer span.End()
+	revisions, err := s.Repo.GetRevisions(ctx, id)
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get history of song with id %d", id)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("not found error: history of song with id %d doesn't exist", id)
+	}
+	var previous []byte
+	for i := range revisions {
+		changes, err := diffSnapshots(previous, revisions[i].Snapshot)
+		if err != nil {
+			tracing.Fail(span, err)
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to compare revisions of song with id %d", id)
+			return nil, err
+		}
+		revisions[i].Changes = changes
+		previous = revisions[i].Snapshot
+	}
+	return revisions, nil
+}
+
+// RevertSong restores song fields from the given revision, the song is restored from trash if needed
+func (s SongService) RevertSong(ctx context.Context, id, revision int) (*models.Song, error) {
+	ctx, span := tracing.Start(ctx, "SongService.RevertSong")
+	defer span.End()
+	rev, err := s.Repo.GetRevision(ctx, id, revision)
+	if err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get revision %d of song with id %d", revision, id)
+		return nil, err
+	}
+	song := &models.Song{}
+	if err := json.Unmarshal(rev.Snapshot, song); err != nil {
+		return nil, err
+	}
+	song.ID = &id
+	song.DeletedAt = nil
+	ctx = utils.WithChangeSource(ctx, utils.SourceRevert)
+	if _, err := s.Repo.Upsert(ctx, song); err != nil {
+		tracing.Fail(span, err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to revert song with id %d to revision %d", id, revision)
+		return nil, err
+	}
+	return song, nil
+}
+
+// diffSnapshots returns fields that differ between two song snapshots, previous may be empty
+func diffSnapshots(previous, current []byte) ([]models.FieldChange, error) {
+	prev := map[string]interface{}{}
+	curr := map[string]interface{}{}
+	if len(previous) > 0 {
+		if err := json.Unmarshal(previous, &prev); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(current, &curr); err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for field := range curr {
+		fields = append(fields, field)
+	}
+	for field := range prev {
+		if _, ok := curr[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []models.FieldChange{}
+	for _, field := range fields {
+		if field == "id" {
+			continue
+		}
+		if !reflect.DeepEqual(prev[field], curr[field]) {
+			changes = append(changes, models.FieldChange{Field: field, Old: prev[field], New: curr[field]})
+		}
+	}
+	return changes, nil
+}
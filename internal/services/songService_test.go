@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+	"music-lib/internal/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memorySongRepo keeps songs in memory, methods other than GetById and Update aren't implemented
+type memorySongRepo struct {
+	repository.ISongRepo
+	songs map[int]models.Song
+}
+
+func (r *memorySongRepo) GetById(ctx context.Context, id int) (*models.Song, error) {
+	song, ok := r.songs[id]
+	if !ok {
+		return nil, fmt.Errorf("not found error: song with id %d doesn't exist", id)
+	}
+	return &song, nil
+}
+
+func (r *memorySongRepo) Update(
+	ctx context.Context, id int, change func(song *models.Song) (*models.Song, error),
+) (*models.Song, error) {
+	song, err := r.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	newSong, err := change(song)
+	if err != nil {
+		return nil, err
+	}
+	r.songs[id] = *newSong
+	return newSong, nil
+}
+
+func allowUpdate(song, newSong *models.Song) error {
+	return nil
+}
+
+func ratedSong() *models.Song {
+	id := 1
+	return &models.Song{
+		ID:          &id,
+		Name:        "Song Name",
+		Artist:      "Song Artist",
+		Lyrics:      "Song Lyrics",
+		ReleaseDate: utils.CustomDate(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+		URL:         "https://song.url",
+		Tags:        []string{"chill"},
+		RatingAvg:   4.5,
+		RatingCount: 2,
+	}
+}
+
+func TestApplyPatchRatedSong(t *testing.T) {
+	patches := []struct {
+		patchType string
+		patch     string
+	}{
+		{utils.MIMEMergePatch, `{"song": "New Name"}`},
+		{utils.MIMEJSONPatch, `[{"op": "replace", "path": "/song", "value": "New Name"}]`},
+	}
+	for _, p := range patches {
+		newSong, err := SongService{}.ApplyPatch(ratedSong(), p.patchType, []byte(p.patch))
+		if err != nil {
+			t.Fatalf("%s: error applying patch to rated song: %v", p.patchType, err)
+		}
+		if newSong.Name != "New Name" || newSong.Artist != "Song Artist" || newSong.Lyrics != "Song Lyrics" {
+			t.Errorf("%s: unexpected patched song %+v", p.patchType, newSong)
+		}
+	}
+}
+
+func TestApplyPatchReadOnlyFields(t *testing.T) {
+	// Rating and tags in the patch don't change the song
+	patch := `{"rating_avg": 1, "rating_count": 100, "tags": ["rock"]}`
+	newSong, err := SongService{}.ApplyPatch(ratedSong(), utils.MIMEMergePatch, []byte(patch))
+	if err != nil {
+		t.Fatalf("Error applying patch: %v", err)
+	}
+	if newSong.RatingAvg != 0 || newSong.RatingCount != 0 || newSong.Tags != nil {
+		t.Errorf("Read-only fields were patched: %+v", newSong)
+	}
+}
+
+func TestApplyPatchUnknownField(t *testing.T) {
+	_, err := SongService{}.ApplyPatch(ratedSong(), utils.MIMEMergePatch, []byte(`{"unknown": 1}`))
+	if err == nil {
+		t.Fatal("Expected error for unknown field")
+	}
+}
+
+func TestPatchSong(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		check     func(song *models.Song) bool
+	}{
+		{
+			"merge patch clears lyrics", utils.MIMEMergePatch, `{"song": "New Name", "lyrics": null}`,
+			func(song *models.Song) bool {
+				return song.Name == "New Name" && song.Lyrics == "" && song.URL == "https://song.url"
+			},
+		},
+		{
+			"merge patch sets genres", utils.MIMEMergePatch, `{"genres": ["Rock", "rock", "Pop"]}`,
+			func(song *models.Song) bool { return strings.Join(song.Genres, ",") == "rock,pop" },
+		},
+		{
+			"json patch", utils.MIMEJSONPatch,
+			`[{"op": "test", "path": "/song", "value": "Song Name"}, {"op": "replace", "path": "/group", "value": "New Artist"}]`,
+			func(song *models.Song) bool { return song.Artist == "New Artist" && song.Name == "Song Name" },
+		},
+		{
+			"json patch removes url", utils.MIMEJSONPatch, `[{"op": "remove", "path": "/url"}]`,
+			func(song *models.Song) bool { return song.URL == "" && song.Lyrics == "Song Lyrics" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memorySongRepo{songs: map[int]models.Song{1: *ratedSong()}}
+			patched, err := SongService{Repo: repo}.PatchSong(context.Background(), 1, tt.patchType, []byte(tt.patch), allowUpdate)
+			if err != nil {
+				t.Fatalf("Error patching song: %v", err)
+			}
+			if !tt.check(patched) {
+				t.Fatalf("Unexpected patched song %+v", patched)
+			}
+			stored := repo.songs[1]
+			if !tt.check(&stored) {
+				t.Fatalf("Patched song wasn't saved: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestPatchSongRejected(t *testing.T) {
+	forbid := func(song, newSong *models.Song) error {
+		return fmt.Errorf("forbidden error: not allowed")
+	}
+	tests := []struct {
+		name      string
+		id        int
+		patchType string
+		patch     string
+		authorize AuthorizeUpdate
+		problem   string
+	}{
+		{"failing test op", 1, utils.MIMEJSONPatch,
+			`[{"op": "test", "path": "/song", "value": "Other Name"}, {"op": "replace", "path": "/song", "value": "New Name"}]`,
+			allowUpdate, "conflict error"},
+		{"unknown field", 1, utils.MIMEMergePatch, `{"unknown": 1}`, allowUpdate, "invalid patch error"},
+		{"unknown field added by json patch", 1, utils.MIMEJSONPatch, `[{"op": "add", "path": "/unknown", "value": 1}]`,
+			allowUpdate, "invalid patch error"},
+		{"id changed", 1, utils.MIMEMergePatch, `{"id": 2}`, allowUpdate, "id can't be changed"},
+		{"required field removed", 1, utils.MIMEMergePatch, `{"song": null}`, allowUpdate, "Song"},
+		{"bad patch type", 1, "application/json", `{}`, allowUpdate, "unsupported patch type"},
+		{"not authorized", 1, utils.MIMEMergePatch, `{"lyrics": "New Lyrics"}`, forbid, "forbidden error"},
+		{"missing song", 2, utils.MIMEMergePatch, `{"song": "New Name"}`, allowUpdate, "not found error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memorySongRepo{songs: map[int]models.Song{1: *ratedSong()}}
+			_, err := SongService{Repo: repo}.PatchSong(context.Background(), tt.id, tt.patchType, []byte(tt.patch), tt.authorize)
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Fatalf("Expected %q error, got %v", tt.problem, err)
+			}
+			if repo.songs[1].Name != "Song Name" || repo.songs[1].Lyrics != "Song Lyrics" {
+				t.Fatalf("Rejected patch changed the song: %+v", repo.songs[1])
+			}
+		})
+	}
+}
+
+func TestUpdateSongKeepsEmptyFields(t *testing.T) {
+	repo := &memorySongRepo{songs: map[int]models.Song{1: *ratedSong()}}
+	var authorized *models.Song
+	authorize := func(song, newSong *models.Song) error {
+		authorized = newSong
+		return nil
+	}
+	updated, err := SongService{Repo: repo}.UpdateSong(context.Background(), 1, &models.Song{Name: "New Name"}, authorize)
+	if err != nil {
+		t.Fatalf("Error updating song: %v", err)
+	}
+	if updated.Name != "New Name" || updated.Lyrics != "Song Lyrics" || updated.Artist != "Song Artist" {
+		t.Fatalf("Unexpected updated song %+v", updated)
+	}
+	// Policy sees the song that is saved, with the stored lyrics
+	if authorized == nil || authorized.Lyrics != "Song Lyrics" {
+		t.Fatalf("Unexpected song authorized %+v", authorized)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"music-lib/internal/db/models"
+	"music-lib/internal/db/repository"
+
+	"github.com/rs/zerolog/log"
+)
+
+type ITagService interface {
+	GetTags(ctx context.Context) ([]models.TagCount, error)
+	AddSongTags(ctx context.Context, songID int, tags []string) (*models.Song, error)
+	RemoveSongTag(ctx context.Context, songID int, tag string) error
+}
+
+type TagService struct {
+	Repo     repository.ITagRepo
+	SongRepo repository.ISongRepo
+}
+
+func NewTagService(tagRepo repository.ITagRepo, songRepo repository.ISongRepo) ITagService {
+	return TagService{tagRepo, songRepo}
+}
+
+func (s TagService) GetTags(ctx context.Context) ([]models.TagCount, error) {
+	tags, err := s.Repo.GetAll(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get tags")
+		return nil, err
+	}
+	return tags, nil
+}
+
+// AddSongTags labels the song with normalized tags and returns the song with all its tags
+func (s TagService) AddSongTags(ctx context.Context, songID int, tags []string) (*models.Song, error) {
+	if err := s.Repo.AddToSong(ctx, songID, models.NormalizeLabels(tags)); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to add tags to song with id %d", songID)
+		return nil, err
+	}
+	song, err := s.SongRepo.GetById(ctx, songID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get song with id %d", songID)
+		return nil, err
+	}
+	return song, nil
+}
+
+func (s TagService) RemoveSongTag(ctx context.Context, songID int, tag string) error {
+	tags := models.NormalizeLabels([]string{tag})
+	if len(tags) == 1 {
+		tag = tags[0]
+	}
+	if err := s.Repo.RemoveFromSong(ctx, songID, tag); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove tag %s from song with id %d", tag, songID)
+		return err
+	}
+	return nil
+}
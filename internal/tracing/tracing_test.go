@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+	"music-lib/internal/config"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSampler(t *testing.T) {
+	none, all := 0.0, 1.0
+	tests := []struct {
+		name  string
+		ratio *float64
+		want  sdktrace.SamplingDecision
+	}{
+		{"not set", nil, sdktrace.RecordAndSample},
+		{"zero", &none, sdktrace.Drop},
+		{"one", &all, sdktrace.RecordAndSample},
+	}
+	traceID := trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Tracing.SampleRatio = tt.ratio
+			result := sampler(cfg).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       traceID,
+				Name:          "GET /songs",
+			})
+			if result.Decision != tt.want {
+				t.Fatalf("Expected decision %v, got %v", tt.want, result.Decision)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"regexp"
+)
+
+type contextKey string
+
+const (
+	actorKey        contextKey = "actor"
+	roleKey         contextKey = "role"
+	libraryKey      contextKey = "library"
+	changeSourceKey contextKey = "change-source"
+	requestIDKey    contextKey = "request-id"
+)
+
+// Roles of users, each role is allowed actions of the roles before it
+const (
+	RoleViewer    = "viewer"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Sources of changes recorded in song history
+const (
+	SourceAPI    = "api"
+	SourceRevert = "revert"
+)
+
+const AnonymousActor = "anonymous"
+
+// HeaderRequestID carries the request ID from clients, in responses and to the music info API
+const HeaderRequestID = "X-Request-ID"
+
+// DefaultLibrary holds songs of requests that don't name a library
+const DefaultLibrary = "default"
+
+var libraryName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// WithActor returns context with the user performing the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns user performing the request or AnonymousActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+// WithRole returns context with the role of the user performing the request
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// RoleFromContext returns role of the user performing the request, RoleViewer by default
+func RoleFromContext(ctx context.Context) string {
+	if role, ok := ctx.Value(roleKey).(string); ok && role != "" {
+		return role
+	}
+	return RoleViewer
+}
+
+// WithLibrary returns context with the library the request works with
+func WithLibrary(ctx context.Context, library string) context.Context {
+	return context.WithValue(ctx, libraryKey, library)
+}
+
+// LibraryFromContext returns library the request works with, DefaultLibrary by default
+func LibraryFromContext(ctx context.Context) string {
+	if library, ok := ctx.Value(libraryKey).(string); ok && library != "" {
+		return library
+	}
+	return DefaultLibrary
+}
+
+// IsLibraryName reports whether name is a valid library name, lowercase letters, digits and hyphens
+// up to 63 characters, so it can be used as a subdomain
+func IsLibraryName(name string) bool {
+	return libraryName.MatchString(name)
+}
+
+// WithChangeSource returns context with the source of changes made with it
+func WithChangeSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, changeSourceKey, source)
+}
+
+// ChangeSourceFromContext returns source of changes, SourceAPI by default
+func ChangeSourceFromContext(ctx context.Context) string {
+	if source, ok := ctx.Value(changeSourceKey).(string); ok && source != "" {
+		return source
+	}
+	return SourceAPI
+}
+
+// WithRequestID returns context with the ID correlating logs and upstream calls of the request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns ID of the request, empty outside of requests
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
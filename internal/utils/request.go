@@ -1,22 +1,104 @@
 package utils
 
 type SongPostRequest struct {
-	Group string `json:"group" validate:"required" example:"Artist or group name"`
-	Song  string `json:"song" validate:"required" example:"Song name"`
+	Group   string          `json:"group" validate:"required" example:"Artist or group name"`
+	Song    string          `json:"song" validate:"required" example:"Song name"`
+	Credits []CreditRequest `json:"credits" validate:"dive"` // Artists credited in addition to group
+	Genres  []string        `json:"genres" validate:"dive,required,max=64" example:"rock"`
+}
+
+type CreditRequest struct {
+	ArtistID *int   `json:"artist_id,omitempty" swaggerignore:"true"` // Ignored, artist is resolved from Group
+	Group    string `json:"group" validate:"required,max=255" example:"Featured artist name"`
+	Role     string `json:"role" validate:"required,oneof=primary featured composer lyricist producer" example:"featured"`
 }
 
 type SongPatchRequest struct {
-	Group       string     `json:"group" example:"Artist or group name"`
-	Song        string     `json:"song" example:"Song name"`
-	Lyrics      string     `json:"lyrics" example:"New lyrics of the song"`
-	ReleaseDate CustomDate `json:"release_date" format:"string" example:"02.01.2006"`
-	URL         string     `json:"url" example:"https://www.youtube.com/watch?v=12345"`
+	Group       string          `json:"group" example:"Artist or group name"`
+	Song        string          `json:"song" example:"Song name"`
+	Lyrics      string          `json:"lyrics" example:"New lyrics of the song"`
+	ReleaseDate CustomDate      `json:"release_date" format:"string" example:"02.01.2006"`
+	URL         string          `json:"url" example:"https://www.youtube.com/watch?v=12345"`
+	Credits     []CreditRequest `json:"credits" validate:"dive"`                               // Replaces additional credits if set
+	Genres      []string        `json:"genres" validate:"dive,required,max=64" example:"rock"` // Replaces genres if set
 }
 
 type SongPutRequest struct {
-	Group       string     `json:"group" validate:"required" example:"Artist or group name"`
-	Song        string     `json:"song" validate:"required" example:"Song name"`
-	Lyrics      string     `json:"lyrics" validate:"required" example:"New lyrics of the song"`
-	ReleaseDate CustomDate `json:"release_date" validate:"required" format:"string" example:"02.01.2006"`
-	URL         string     `json:"url" validate:"required" example:"https://www.youtube.com/watch?v=12345"`
+	Group       string          `json:"group" validate:"required" example:"Artist or group name"`
+	Song        string          `json:"song" validate:"required" example:"Song name"`
+	Lyrics      string          `json:"lyrics" validate:"required" example:"New lyrics of the song"`
+	ReleaseDate CustomDate      `json:"release_date" validate:"required" format:"string" example:"02.01.2006"`
+	URL         string          `json:"url" validate:"required" example:"https://www.youtube.com/watch?v=12345"`
+	Credits     []CreditRequest `json:"credits" validate:"dive"`                               // Replaces additional credits if set
+	Genres      []string        `json:"genres" validate:"dive,required,max=64" example:"rock"` // Replaces genres if set
+}
+
+const (
+	MIMEMergePatch = "application/merge-patch+json" // RFC 7396
+	MIMEJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// SongDocument is a full representation of a song, JSON Patch and JSON Merge Patch are applied to it
+type SongDocument struct {
+	ID          *int            `json:"id"`
+	ArtistID    *int            `json:"artist_id"` // Ignored, artist is resolved from Group
+	Group       string          `json:"group" validate:"required"`
+	Song        string          `json:"song" validate:"required"`
+	Lyrics      string          `json:"lyrics"`
+	ReleaseDate CustomDate      `json:"release_date" validate:"required"`
+	URL         string          `json:"url" validate:"omitempty,url"`
+	Credits     []CreditRequest `json:"credits" validate:"dive"`
+	Genres      []string        `json:"genres" validate:"dive,required,max=64"`
+	Tags        []string        `json:"tags"`         // Ignored, tags are managed with tag endpoints
+	RatingAvg   float64         `json:"rating_avg"`   // Ignored, ratings are managed with rating endpoints
+	RatingCount int             `json:"rating_count"` // Ignored
+}
+
+type TagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,dive,required,max=64" example:"chill"`
+}
+
+type RatingRequest struct {
+	Rating int `json:"rating" validate:"required,min=1,max=5" example:"4"`
+}
+
+type ArtistRequest struct {
+	Name    string   `json:"name" validate:"required,max=255" example:"The Beatles"`
+	Aliases []string `json:"aliases" validate:"dive,required,max=255" example:"Beatles"`
+	Country string   `json:"country" validate:"omitempty,iso3166_1_alpha2" example:"GB"`
+}
+
+type AlbumRequest struct {
+	Title       string     `json:"title" validate:"required,max=255" example:"Abbey Road"`
+	Group       string     `json:"group" validate:"required" example:"The Beatles"`
+	ReleaseDate CustomDate `json:"release_date" validate:"required" format:"string" example:"26.09.1969"`
+	CoverURL    string     `json:"cover_url" validate:"omitempty,url,max=255" example:"https://example.com/cover.jpg"`
+}
+
+type AlbumTrackRequest struct {
+	SongID      int `json:"song_id" validate:"required" example:"1"`
+	TrackNumber int `json:"track_number" validate:"required,min=1" example:"1"`
+	DiscNumber  int `json:"disc_number" validate:"omitempty,min=1" example:"1"` // 1 if not set
+}
+
+type PlaylistRequest struct {
+	Name        string `json:"name" validate:"required,max=255" example:"Road trip"`
+	Description string `json:"description" validate:"max=1000" example:"Songs for a long drive"`
+	Visibility  string `json:"visibility" validate:"omitempty,oneof=public private" example:"private"` // Private by default
+}
+
+type PlaylistEntryRequest struct {
+	SongID   int `json:"song_id" validate:"required" example:"1"`
+	Position int `json:"position" validate:"min=0" example:"1"` // Appended to the end if not set or past the end
+}
+
+type PlaylistMoveRequest struct {
+	Position int `json:"position" validate:"required,min=1" example:"1"` // Moved to the end if past the end
+}
+
+type APIKeyRequest struct {
+	Name      string   `json:"name" validate:"required,max=255" example:"nightly-import"`
+	Scopes    []string `json:"scopes" validate:"required,min=1,dive,oneof=read write admin" example:"read,write"`
+	Library   string   `json:"library" example:"team-a"`                  // the key can access any library if not set
+	ExpiresIn int      `json:"expires_in" validate:"min=0" example:"720"` // hours, the key never expires if not set
 }
@@ -3,4 +3,6 @@ package utils
 type Response struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	// Set in error responses, so the failed request can be found in logs
+	RequestID string `json:"requestId,omitempty"`
 }